@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/Friday-fighting/runninghub_tools/runninghub_client_utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultHistoryDB 任务记录数据库路径, 优先使用 RUNNINGHUB_HISTORY_DB
+func defaultHistoryDB() string {
+	if v := os.Getenv("RUNNINGHUB_HISTORY_DB"); v != "" {
+		return v
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "history.db"
+	}
+	return filepath.Join(home, ".runninghub", "history.db")
+}
+
+// parseHistoryTime 支持 2006-01-02, RFC3339 与 168h 形式的相对时间(表示多久之前)
+func parseHistoryTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func runHistory(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	db := fs.String("db", defaultHistoryDB(), "任务记录数据库")
+	workflowId := fs.String("workflow", "", "工作流 id")
+	jobId := fs.String("job", "", "作业 id")
+	status := fs.String("status", "", "任务状态, 逗号分隔, 如 SUCCESS,FAILED")
+	category := fs.String("category", "", "失败分类")
+	tags := fs.String("tag", "", "标签, 逗号分隔, 需全部包含")
+	since := fs.String("since", "", "开始时间, 如 2025-01-02, RFC3339 或 168h(7 天前)")
+	until := fs.String("until", "", "结束时间, 格式同 -since")
+	minMoney := fs.Float64("min-money", 0, "最低金额")
+	maxMoney := fs.Float64("max-money", 0, "最高金额")
+	limit := fs.Int("limit", 0, "返回条数上限")
+	desc := fs.Bool("desc", false, "按时间倒序")
+	format := fs.String("format", "table", "输出格式 table / csv / json")
+	summary := fs.Bool("summary", false, "只输出汇总")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	switch *format {
+	case "table", "json":
+	case "csv":
+		if *summary {
+			return fmt.Errorf("-summary only supports -format table or json")
+		}
+	default:
+		return fmt.Errorf("unsupported format: %s", *format)
+	}
+
+	q := &runninghub_client_utils.TaskHistoryQuery{
+		WorkflowId:      *workflowId,
+		JobId:           *jobId,
+		FailureCategory: *category,
+		Tags:            splitList(*tags),
+		MinCost:         runninghub_client_utils.Cost{Money: *minMoney},
+		MaxCost:         runninghub_client_utils.Cost{Money: *maxMoney},
+		Desc:            *desc,
+		Limit:           *limit,
+	}
+	for _, v := range splitList(*status) {
+		s, ok := runninghub_client_utils.ParseTaskStatus(v)
+		if !ok {
+			return fmt.Errorf("unknown task status: %s", v)
+		}
+		q.Status = append(q.Status, s)
+	}
+	var err error
+	if q.Since, err = parseHistoryTime(*since); err != nil {
+		return fmt.Errorf("invalid -since: %w", err)
+	}
+	if q.Until, err = parseHistoryTime(*until); err != nil {
+		return fmt.Errorf("invalid -until: %w", err)
+	}
+
+	// 只读打开, 不创建文件; 写入进程未退出时等待超时后报错
+	store, err := runninghub_client_utils.NewBoltStoreWithOption(*db, &runninghub_client_utils.BoltStoreOption{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	history := runninghub_client_utils.NewTaskHistory(runninghub_client_utils.NewTaskStore(store))
+	records, err := history.Query(ctx, q)
+	if err != nil {
+		return err
+	}
+
+	if *summary {
+		res := runninghub_client_utils.SummarizeTaskRecords(records)
+		if *format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(res)
+		}
+		fmt.Printf("total: %d, succeeded: %d, failed: %d, money: %.4f, coins: %.2f\n",
+			res.Count, res.Succeeded, res.Failed, res.Cost.Money, res.Cost.Coins)
+		workflowIds := make([]string, 0, len(res.ByWorkflow))
+		for k := range res.ByWorkflow {
+			workflowIds = append(workflowIds, k)
+		}
+		sort.Strings(workflowIds)
+		for _, k := range workflowIds {
+			v := res.ByWorkflow[k]
+			fmt.Printf("  %-24s %6d %6d %6d %10.4f %10.2f\n", k, v.Count, v.Succeeded, v.Failed, v.Cost.Money, v.Cost.Coins)
+		}
+		return nil
+	}
+
+	switch *format {
+	case "csv":
+		return runninghub_client_utils.WriteTaskRecordsCSV(os.Stdout, records)
+	case "json":
+		return runninghub_client_utils.WriteTaskRecordsJSON(os.Stdout, records)
+	default:
+		for _, v := range records {
+			t := v.CreatedAt
+			if t.IsZero() {
+				t = v.FinishedAt
+			}
+			fmt.Printf("%-20s %-22s %-24s %-8s %-20s %10.4f %s\n",
+				t.Format("2006-01-02 15:04:05"), v.TaskId, v.WorkflowId, v.Status, v.FailureCategory, v.Cost.Money, strings.Join(v.Tags, ","))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Friday-fighting/runninghub_tools/runninghub_client_utils"
+	"os"
+	"os/signal"
+)
+
+const usage = `rhctl RunningHub 命令行工具
+
+Usage:
+  rhctl lora sync [flags] <dir>
+  rhctl history [flags]
+
+Environment:
+  RUNNINGHUB_API_KEY     RunningHub api key
+  RUNNINGHUB_HOST        RunningHub host, 默认 www.runninghub.cn
+  RUNNINGHUB_HISTORY_DB  任务记录数据库, 默认 ~/.runninghub/history.db
+`
+
+// errUsage 参数错误, 已输出用法
+var errUsage = errors.New("usage error")
+
+func main() {
+	os.Exit(exitCode(realMain()))
+}
+
+// realMain 返回前执行所有 defer, 退出码由 main 决定
+func realMain() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return run(ctx, os.Args[1:])
+}
+
+func exitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	if len(args) >= 1 && args[0] == "history" {
+		return runHistory(ctx, args[1:])
+	}
+	if len(args) >= 2 && args[0]+" "+args[1] == "lora sync" {
+		return runLoraSync(ctx, args[2:])
+	}
+	fmt.Fprint(os.Stderr, usage)
+	return errUsage
+}
+
+// parseFlags 解析参数, 出错时 FlagSet 已输出错误与用法
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return errUsage
+	}
+	return err
+}
+
+// newClient 按环境变量创建客户端, requireKey 为 false 时允许不设置 api key
+func newClient(requireKey bool) (*runninghub_client_utils.RunningHubClient, error) {
+	apiKey := os.Getenv("RUNNINGHUB_API_KEY")
+	if apiKey == "" && requireKey {
+		return nil, fmt.Errorf("RUNNINGHUB_API_KEY must be set")
+	}
+	return runninghub_client_utils.NewClient(&runninghub_client_utils.RunningHubClientConfig{
+		ApiKey: apiKey,
+		Host:   os.Getenv("RUNNINGHUB_HOST"),
+	}), nil
+}
+
+func runLoraSync(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("lora sync", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "只比对不上传")
+	manifest := fs.String("manifest", "", "清单文件, 默认 <dir>/.runninghub_lora_manifest.json")
+	concurrency := fs.Int("concurrency", 4, "并行计算 MD5 数")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: rhctl lora sync [flags] <dir>")
+		return errUsage
+	}
+	// dry-run 不访问网络, 无需 api key
+	client, err := newClient(!*dryRun)
+	if err != nil {
+		return err
+	}
+	res, err := client.SyncLoraDir(ctx, &runninghub_client_utils.SyncLoraDirInput{
+		Dir:          fs.Arg(0),
+		ManifestPath: *manifest,
+		Concurrency:  *concurrency,
+		DryRun:       *dryRun,
+		Progress: func(loraName string, done, total int64) {
+			fmt.Fprintf(os.Stderr, "\r%s %d/%d", loraName, done, total)
+			if done == total {
+				fmt.Fprintln(os.Stderr)
+			}
+		},
+	})
+	if res != nil {
+		for _, v := range res.Items {
+			status := v.Action
+			if v.Uploaded {
+				status += ", uploaded"
+			}
+			if v.Err != nil {
+				status += ", error: " + v.Err.Error()
+			}
+			fmt.Printf("%-10s %-40s %s\n", v.LoraName, v.LocalPath, status)
+		}
+	}
+	return err
+}
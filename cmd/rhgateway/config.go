@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Friday-fighting/runninghub_tools/runninghub_client_utils"
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"os"
+)
+
+const (
+	resultUrlModeProxy  = "proxy"  // 返回网关签名链接, 由网关代理下载
+	resultUrlModeDirect = "direct" // 直接返回平台链接
+)
+
+// teamConfig 团队配置, 为 0 的限制项不限制
+type teamConfig struct {
+	Name                  string   `json:"name"`
+	Token                 string   `json:"token"`
+	MaxConcurrent         int      `json:"maxConcurrent"`         // 同时执行的任务数上限
+	DailyTasks            int      `json:"dailyTasks"`            // 每日提交任务数上限
+	DailyMoney            float64  `json:"dailyMoney"`            // 每日花费金额上限, 按已花费加执行中任务的预估计算
+	DailyCoins            float64  `json:"dailyCoins"`            // 每日花费 RH 币上限, 计算方式同 dailyMoney
+	Workflows             []string `json:"workflows"`             // 允许使用的工作流, 为空时不限制
+	AllowWorkflowOverride bool     `json:"allowWorkflowOverride"` // 允许在请求中通过 workflow 覆盖工作流内容, 默认不允许
+	InstanceTypes         []string `json:"instanceTypes"`         // 允许使用的非默认机型, 如 plus, 为空时只能使用默认机型
+}
+
+type config struct {
+	Listen            string        `json:"listen"`            // 监听地址, 默认 :8080
+	ApiKey            string        `json:"apiKey"`            // RunningHub api key, 可由 RUNNINGHUB_API_KEY 覆盖
+	Host              string        `json:"host"`              // RunningHub host, 可由 RUNNINGHUB_HOST 覆盖
+	UseHttp           bool          `json:"useHttp"`           // 使用 http 访问 RunningHub, 用于本地调试
+	DB                string        `json:"db"`                // 任务归属记录数据库, 默认 rhgateway.db
+	Lang              string        `json:"lang"`              // 错误提示语言
+	PublicUrl         string        `json:"publicUrl"`         // 生成代理链接使用的外部地址, 为空时使用请求的 Host
+	SignKey           string        `json:"signKey"`           // 代理链接签名密钥, 可由 RHGATEWAY_SIGN_KEY 覆盖, 为空时随机生成, 重启后旧链接失效
+	ResultUrlMode     string        `json:"resultUrlMode"`     // proxy / direct, 默认 proxy
+	ResultUrlTTL      int           `json:"resultUrlTtl"`      // 代理链接有效期(秒), 默认 3600
+	MaxUploadBytes    int64         `json:"maxUploadBytes"`    // 单次上传大小上限, 默认 100MB
+	ReconcileInterval int           `json:"reconcileInterval"` // 同步执行中任务状态的间隔(秒), 默认 15
+	Teams             []*teamConfig `json:"teams"`
+
+	// DefaultEstimate 工作流无历史花费时的单任务预估, 用于 dailyMoney / dailyCoins 的占用
+	DefaultEstimate runninghub_client_utils.Cost `json:"defaultEstimate"`
+}
+
+// loadConfig 加载配置文件, 支持 json/yaml/toml 等格式
+func loadConfig(path string) (*config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	j, err := gjson.LoadContent(content)
+	if err != nil {
+		return nil, fmt.Errorf("load config fail: %w", err)
+	}
+	cfg := &config{}
+	if err = j.Scan(cfg); err != nil {
+		return nil, fmt.Errorf("load config fail: %w", err)
+	}
+	if v := os.Getenv("RUNNINGHUB_API_KEY"); v != "" {
+		cfg.ApiKey = v
+	}
+	if v := os.Getenv("RUNNINGHUB_HOST"); v != "" {
+		cfg.Host = v
+	}
+	if v := os.Getenv("RHGATEWAY_SIGN_KEY"); v != "" {
+		cfg.SignKey = v
+	}
+	if cfg.Listen == "" {
+		cfg.Listen = ":8080"
+	}
+	if cfg.DB == "" {
+		cfg.DB = "rhgateway.db"
+	}
+	if cfg.ResultUrlMode == "" {
+		cfg.ResultUrlMode = resultUrlModeProxy
+	}
+	if cfg.ResultUrlTTL <= 0 {
+		cfg.ResultUrlTTL = 3600
+	}
+	if cfg.MaxUploadBytes <= 0 {
+		cfg.MaxUploadBytes = 100 << 20
+	}
+	if cfg.ReconcileInterval <= 0 {
+		cfg.ReconcileInterval = 15
+	}
+	return cfg, cfg.validate()
+}
+
+func (c *config) validate() error {
+	if c.ApiKey == "" {
+		return fmt.Errorf("apiKey or RUNNINGHUB_API_KEY must be set")
+	}
+	if c.ResultUrlMode != resultUrlModeProxy && c.ResultUrlMode != resultUrlModeDirect {
+		return fmt.Errorf("unsupported resultUrlMode: %s", c.ResultUrlMode)
+	}
+	if len(c.Teams) == 0 {
+		return fmt.Errorf("at least one team must be configured")
+	}
+	names, tokens := make(map[string]bool), make(map[string]bool)
+	for i, v := range c.Teams {
+		if v.Name == "" || v.Token == "" {
+			return fmt.Errorf("team(%d) name and token cannot be empty", i)
+		}
+		if names[v.Name] || tokens[v.Token] {
+			return fmt.Errorf("team(%s) name or token is duplicated", v.Name)
+		}
+		names[v.Name], tokens[v.Token] = true, true
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Friday-fighting/runninghub_tools/runninghub_client_utils"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const usage = `rhgateway RunningHub HTTP 网关, 团队通过 token 调用, 无需持有 api key
+
+Usage:
+  rhgateway -config rhgateway.yaml
+
+Environment:
+  RUNNINGHUB_API_KEY  RunningHub api key, 覆盖配置文件
+  RUNNINGHUB_HOST     RunningHub host, 覆盖配置文件
+  RHGATEWAY_SIGN_KEY  代理链接签名密钥, 覆盖配置文件
+`
+
+func main() {
+	configPath := flag.String("config", "rhgateway.yaml", "配置文件")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, *configPath); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, configPath string) error {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	store, err := runninghub_client_utils.NewBoltStore(cfg.DB)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	// 只用于按工作流历史花费预估占用额度, 限额由团队配置控制
+	budget := runninghub_client_utils.NewBudget(&runninghub_client_utils.BudgetConfig{
+		DefaultEstimate: cfg.DefaultEstimate,
+		Store:           store,
+	})
+	g := &gateway{
+		cfg: cfg,
+		client: runninghub_client_utils.NewClient(&runninghub_client_utils.RunningHubClientConfig{
+			ApiKey:     cfg.ApiKey,
+			Host:       cfg.Host,
+			UseHttpReq: cfg.UseHttp,
+			Lang:       cfg.Lang,
+		}),
+		tasks:   runninghub_client_utils.NewTaskStore(store),
+		budget:  budget,
+		teams:   make(map[string]*teamState, len(cfg.Teams)),
+		signKey: []byte(cfg.SignKey),
+		metrics: newMetrics(),
+	}
+	if len(g.signKey) == 0 {
+		g.signKey = make([]byte, 32)
+		rand.Read(g.signKey)
+		log.Print("signKey is not set, proxied result urls become invalid after restart")
+	}
+	for _, v := range cfg.Teams {
+		g.teams[v.Name] = newTeamState(v)
+	}
+	if err = restoreTeams(ctx, g.tasks, g.budget, g.teams); err != nil {
+		return err
+	}
+	go g.reconcile(ctx)
+
+	server := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           g.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("rhgateway listening on %s", cfg.Listen)
+		errCh <- server.ListenAndServe()
+	}()
+	select {
+	case err = <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
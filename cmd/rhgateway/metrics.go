@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// metrics 以 Prometheus 文本格式输出的计数器
+type metrics struct {
+	mu       sync.Mutex
+	counters map[string]map[string]float64 // 指标名 -> 标签串 -> 值
+	help     map[string]string
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		counters: make(map[string]map[string]float64),
+		help: map[string]string{
+			"rhgateway_http_requests_total":   "HTTP requests by route and status code.",
+			"rhgateway_tasks_submitted_total": "Tasks submitted to RunningHub by team.",
+			"rhgateway_tasks_rejected_total":  "Tasks rejected by the gateway by team and reason.",
+			"rhgateway_tasks_finished_total":  "Tasks observed finished by team and status.",
+			"rhgateway_upstream_errors_total": "RunningHub API errors by operation.",
+		},
+	}
+}
+
+// labels 按 key=value 成对传入
+func labels(kv ...string) string {
+	parts := make([]string, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(kv[i+1])
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, kv[i], value))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (m *metrics) inc(name string, labels string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	values, ok := m.counters[name]
+	if !ok {
+		values = make(map[string]float64)
+		m.counters[name] = values
+	}
+	values[labels]++
+}
+
+// gauge 渲染时实时计算的指标
+type gauge struct {
+	name   string
+	help   string
+	labels string
+	value  float64
+}
+
+func (m *metrics) write(w io.Writer, gauges []gauge) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.counters))
+	for name := range m.counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, m.help[name], name)
+		keys := make([]string, 0, len(m.counters[name]))
+		for k := range m.counters[name] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(w, "%s{%s} %g\n", name, k, m.counters[name][k])
+		}
+	}
+	written := make(map[string]bool)
+	for _, g := range gauges {
+		if !written[g.name] {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+			written[g.name] = true
+		}
+		fmt.Fprintf(w, "%s{%s} %g\n", g.name, g.labels, g.value)
+	}
+}
@@ -0,0 +1,586 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Friday-fighting/runninghub_tools/runninghub_client_utils"
+	"github.com/Friday-fighting/runninghub_tools/utility"
+	"hash/fnv"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type teamContextKey struct{}
+
+type gateway struct {
+	cfg     *config
+	client  *runninghub_client_utils.RunningHubClient
+	tasks   *runninghub_client_utils.TaskStore
+	budget  *runninghub_client_utils.Budget // 各工作流的预估花费
+	teams   map[string]*teamState           // 团队名 -> 状态
+	signKey []byte
+	metrics *metrics
+
+	taskLocks [64]sync.Mutex // 按任务 id 分片, 串行化同一任务记录的刷新
+}
+
+type errorRes struct {
+	Error string `json:"error"`
+	Code  int    `json:"code,omitempty"` // RunningHub 错误码
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (g *gateway) writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, &errorRes{Error: msg})
+}
+
+// writeUpstreamError 将 RunningHub 接口错误转为响应, 并发已满时返回 429
+func (g *gateway) writeUpstreamError(w http.ResponseWriter, op string, err error) {
+	g.metrics.inc("rhgateway_upstream_errors_total", labels("op", op))
+	var apiErr *runninghub_client_utils.ApiError
+	if errors.As(err, &apiErr) {
+		status := http.StatusBadGateway
+		if apiErr.Code == 421 || apiErr.Code == 415 {
+			status = http.StatusTooManyRequests
+		}
+		writeJSON(w, status, &errorRes{Error: apiErr.Localized(g.cfg.Lang), Code: apiErr.Code})
+		return
+	}
+	g.writeError(w, http.StatusBadGateway, err.Error())
+}
+
+func (g *gateway) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", g.handleHealth)
+	mux.HandleFunc("GET /metrics", g.handleMetrics)
+	mux.HandleFunc("GET /v1/files/{taskId}/{index}", g.handleFile)
+	mux.Handle("POST /v1/tasks", g.auth(g.handleSubmit))
+	mux.Handle("GET /v1/tasks/{taskId}", g.auth(g.handleStatus))
+	mux.Handle("GET /v1/tasks/{taskId}/result", g.auth(g.handleResult))
+	mux.Handle("POST /v1/tasks/{taskId}/cancel", g.auth(g.handleCancel))
+	mux.Handle("POST /v1/uploads", g.auth(g.handleUpload))
+	mux.Handle("GET /v1/workflows/{workflowId}/inputs", g.auth(g.handleWorkflowInputs))
+	return g.instrument(mux)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// instrument 按路由与状态码统计请求数
+func (g *gateway) instrument(next *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		_, route := next.Handler(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		g.metrics.inc("rhgateway_http_requests_total", labels("route", route, "status", strconv.Itoa(rec.status)))
+	})
+}
+
+// auth 按 Authorization: Bearer <token> 识别团队
+func (g *gateway) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok {
+			for _, t := range g.teams {
+				if subtle.ConstantTimeCompare([]byte(token), []byte(t.cfg.Token)) == 1 {
+					next(w, r.WithContext(context.WithValue(r.Context(), teamContextKey{}, t)))
+					return
+				}
+			}
+		}
+		g.writeError(w, http.StatusUnauthorized, "invalid token")
+	})
+}
+
+func teamFromContext(ctx context.Context) *teamState {
+	t, _ := ctx.Value(teamContextKey{}).(*teamState)
+	return t
+}
+
+// ownedRecord 获取任务记录并校验归属, 失败时已写入响应
+func (g *gateway) ownedRecord(w http.ResponseWriter, r *http.Request) (*runninghub_client_utils.TaskRecord, bool) {
+	record, ok, err := g.tasks.Get(r.Context(), r.PathValue("taskId"))
+	if err != nil {
+		g.writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	// 不属于该团队的任务与不存在的任务一样处理
+	if !ok || teamOfRecord(record) != teamFromContext(r.Context()).cfg.Name {
+		g.writeError(w, http.StatusNotFound, "task not found")
+		return nil, false
+	}
+	return record, true
+}
+
+func (g *gateway) handleHealth(w http.ResponseWriter, r *http.Request) {
+	res := map[string]interface{}{"status": "ok"}
+	// deep=1 时检查 RunningHub 是否可用
+	if r.URL.Query().Get("deep") == "1" {
+		if _, err := g.client.GetAccountInfo(r.Context()); err != nil {
+			res["status"], res["error"] = "unavailable", err.Error()
+			writeJSON(w, http.StatusServiceUnavailable, res)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (g *gateway) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(g.teams))
+	for name := range g.teams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	usages := make([]teamUsage, len(names))
+	for i, name := range names {
+		usages[i] = g.teams[name].usage()
+	}
+	var gauges []gauge
+	for _, v := range []struct {
+		name  string
+		help  string
+		value func(u teamUsage) float64
+	}{
+		{"rhgateway_team_inflight_tasks", "Tasks in flight by team.", func(u teamUsage) float64 { return float64(u.Inflight) }},
+		{"rhgateway_team_daily_tasks", "Tasks submitted today by team.", func(u teamUsage) float64 { return float64(u.DayTasks) }},
+		{"rhgateway_team_daily_money", "Money spent today by finished tasks by team.", func(u teamUsage) float64 { return u.DayCost.Money }},
+		{"rhgateway_team_daily_coins", "Coins spent today by finished tasks by team.", func(u teamUsage) float64 { return u.DayCost.Coins }},
+		{"rhgateway_team_reserved_money", "Estimated money of tasks in flight by team.", func(u teamUsage) float64 { return u.Reserved.Money }},
+		{"rhgateway_team_reserved_coins", "Estimated coins of tasks in flight by team.", func(u teamUsage) float64 { return u.Reserved.Coins }},
+	} {
+		for i, name := range names {
+			gauges = append(gauges, gauge{name: v.name, help: v.help, labels: labels("team", name), value: v.value(usages[i])})
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	g.metrics.write(w, gauges)
+}
+
+// submitReq 提交任务请求, 与 CreateTaskReq 相同但不含 apiKey
+type submitReq struct {
+	WorkflowId       string                              `json:"workflowId"`
+	NodeInfoList     []*runninghub_client_utils.NodeInfo `json:"nodeInfoList"`
+	WebhookUrl       string                              `json:"webhookUrl"`
+	InstanceType     string                              `json:"instanceType"`
+	UsePersonalQueue bool                                `json:"usePersonalQueue"`
+	AddMetadata      bool                                `json:"addMetadata"`
+	Workflow         string                              `json:"workflow"`
+	Tags             []string                            `json:"tags"`
+}
+
+func (g *gateway) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	team := teamFromContext(r.Context())
+	var in submitReq
+	if err := json.NewDecoder(io.LimitReader(r.Body, 10<<20)).Decode(&in); err != nil {
+		g.writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if err := validateSubmit(&in); err != nil {
+		g.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	estimate, err := g.budget.Estimate(r.Context(), in.WorkflowId)
+	if err != nil {
+		g.writeError(w, http.StatusInternalServerError, "estimate cost fail: "+err.Error())
+		return
+	}
+	if reason, ok := team.reserve(in.WorkflowId, in.InstanceType, in.Workflow != "", estimate); !ok {
+		g.metrics.inc("rhgateway_tasks_rejected_total", labels("team", team.cfg.Name, "reason", reason))
+		status := http.StatusTooManyRequests
+		if reason == rejectReasonWorkflow || reason == rejectReasonInstanceType {
+			status = http.StatusForbidden
+		}
+		g.writeError(w, status, "rejected by gateway: "+reason)
+		return
+	}
+	req := &runninghub_client_utils.CreateTaskReq{
+		WorkflowId:       in.WorkflowId,
+		NodeInfoList:     in.NodeInfoList,
+		WebhookUrl:       in.WebhookUrl,
+		InstanceType:     in.InstanceType,
+		UsePersonalQueue: in.UsePersonalQueue,
+		AddMetadata:      in.AddMetadata,
+		Workflow:         in.Workflow,
+	}
+	res, err := g.client.CreateTask(r.Context(), req)
+	if err != nil {
+		team.release("", estimate)
+		g.writeUpstreamError(w, "CreateTask", err)
+		return
+	}
+	team.release(res.TaskId, estimate)
+	g.metrics.inc("rhgateway_tasks_submitted_total", labels("team", team.cfg.Name))
+	record := &runninghub_client_utils.TaskRecord{
+		TaskId:     res.TaskId,
+		WorkflowId: in.WorkflowId,
+		Request:    req,
+		Status:     runninghub_client_utils.TaskStatusQueued,
+		Tags:       append([]string{teamTag(team.cfg.Name)}, in.Tags...),
+		CreatedAt:  time.Now(),
+	}
+	if status, ok := runninghub_client_utils.ParseTaskStatus(res.TaskStatus); ok {
+		record.Status = status
+	}
+	if err = g.tasks.Save(r.Context(), record); err != nil {
+		// 没有记录时团队无法查询该任务, 后台同步也无法释放名额, 取消任务并按失败返回
+		log.Printf("save task(%s) record fail: %v", res.TaskId, err)
+		if cancelRes := g.client.CancelTaskWithResult(context.WithoutCancel(r.Context()), res.TaskId); !cancelRes.Ok() {
+			log.Printf("cancel task(%s) without record fail: %v", res.TaskId, cancelRes.Err)
+		}
+		team.finish(res.TaskId, record.CreatedAt, runninghub_client_utils.Cost{})
+		g.writeError(w, http.StatusInternalServerError, "save task record fail: "+err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"taskId":     res.TaskId,
+		"taskStatus": record.Status,
+		"promptTips": res.PromptTips,
+	})
+}
+
+// validateSubmit 校验提交参数, 参数错误返回 400 而不是按上游错误返回 502
+func validateSubmit(in *submitReq) error {
+	if in.WorkflowId == "" {
+		return errors.New("workflowId cannot be empty")
+	}
+	if len(in.NodeInfoList) == 0 && in.Workflow == "" {
+		return errors.New("nodeInfoList or workflow is required")
+	}
+	for i, v := range in.NodeInfoList {
+		if v == nil || v.NodeId == "" || v.FieldName == "" {
+			return fmt.Errorf("nodeInfoList[%d] nodeId and fieldName cannot be empty", i)
+		}
+	}
+	if in.InstanceType != runninghub_client_utils.InstanceTypeDefault && in.InstanceType != runninghub_client_utils.InstanceTypePlus {
+		return fmt.Errorf("unsupported instanceType: %s", in.InstanceType)
+	}
+	if in.Workflow != "" {
+		if err := (&runninghub_client_utils.CreateTaskReq{}).SetWorkflow(in.Workflow); err != nil {
+			return fmt.Errorf("invalid workflow: %w", err)
+		}
+	}
+	return nil
+}
+
+// taskLock 返回任务对应的分片锁
+func (g *gateway) taskLock(taskId string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(taskId))
+	return &g.taskLocks[h.Sum32()%uint32(len(g.taskLocks))]
+}
+
+// refresh 查询任务状态与结果, 任务结束时更新记录并释放团队并发
+// 后台同步与查询请求可能同时刷新同一任务, 在任务锁内以存储中的最新记录为准更新, 已结束的记录不会被覆盖
+func (g *gateway) refresh(ctx context.Context, record *runninghub_client_utils.TaskRecord) (*runninghub_client_utils.GetTaskStatusAndResultRes, error) {
+	res, err := g.client.GetTaskStatusAndResult(ctx, record.TaskId)
+	if err != nil {
+		return nil, err
+	}
+	mu := g.taskLock(record.TaskId)
+	mu.Lock()
+	defer mu.Unlock()
+	if stored, ok, err := g.tasks.Get(ctx, record.TaskId); err != nil {
+		return nil, err
+	} else if ok {
+		*record = *stored
+	}
+	if res.Status.IsTerminal() && !record.Status.IsTerminal() {
+		record.Status = res.Status
+		record.Code = res.Code
+		record.Msg = res.Msg
+		record.Cost = runninghub_client_utils.TaskCostFromResult(res.SuccessItems)
+		record.FinishedAt = time.Now()
+		if res.Status == runninghub_client_utils.TaskStatusFailed {
+			record.FailureCategory = runninghub_client_utils.DefaultFailureClassifier().Classify(res.FailedReason).Category
+		}
+		if err = g.tasks.Save(ctx, record); err != nil {
+			log.Printf("save task(%s) record fail: %v", record.TaskId, err)
+		}
+		if t, ok := g.teams[teamOfRecord(record)]; ok && t.finish(record.TaskId, record.CreatedAt, record.Cost) {
+			g.metrics.inc("rhgateway_tasks_finished_total", labels("team", t.cfg.Name, "status", string(res.Status)))
+			if err = g.budget.ObserveCost(ctx, record.WorkflowId, record.Cost); err != nil {
+				log.Printf("save workflow(%s) cost estimate fail: %v", record.WorkflowId, err)
+			}
+		}
+	} else if res.Status != runninghub_client_utils.TaskStatusUnknown && res.Status != record.Status {
+		// 状态回退(如 RUNNING -> QUEUED 或已结束后再次 RUNNING)时保留原记录
+		if err = runninghub_client_utils.ValidateTaskStatusTransition(record.Status, res.Status); err != nil {
+			log.Printf("ignore task(%s) status: %v", record.TaskId, err)
+			return res, nil
+		}
+		record.Status = res.Status
+		if err = g.tasks.Save(ctx, record); err != nil {
+			log.Printf("save task(%s) record fail: %v", record.TaskId, err)
+		}
+	}
+	return res, nil
+}
+
+func (g *gateway) handleStatus(w http.ResponseWriter, r *http.Request) {
+	record, ok := g.ownedRecord(w, r)
+	if !ok {
+		return
+	}
+	if !record.Status.IsTerminal() {
+		if _, err := g.refresh(r.Context(), record); err != nil {
+			g.writeUpstreamError(w, "GetTaskStatus", err)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, record)
+}
+
+type resultOutput struct {
+	Index    int    `json:"index"`
+	NodeId   string `json:"nodeId"`
+	FileType string `json:"fileType"`
+	Url      string `json:"url"`
+}
+
+func (g *gateway) handleResult(w http.ResponseWriter, r *http.Request) {
+	record, ok := g.ownedRecord(w, r)
+	if !ok {
+		return
+	}
+	res, err := g.refresh(r.Context(), record)
+	if err != nil {
+		g.writeUpstreamError(w, "GetTaskResult", err)
+		return
+	}
+	out := map[string]interface{}{
+		"taskId": record.TaskId,
+		"status": res.Status,
+		"code":   res.Code,
+		"msg":    res.Msg,
+		"cost":   runninghub_client_utils.TaskCostFromResult(res.SuccessItems),
+	}
+	if res.Status == runninghub_client_utils.TaskStatusFailed {
+		info := g.client.GetErrorInfo(res.Code, res.Msg, res.FailedReason)
+		out["error"] = info
+	}
+	outputs := make([]*resultOutput, 0, len(res.SuccessItems))
+	expires := time.Now().Add(time.Duration(g.cfg.ResultUrlTTL) * time.Second).Unix()
+	for i, v := range res.SuccessItems {
+		if v == nil {
+			continue
+		}
+		output := &resultOutput{Index: i, NodeId: v.NodeId, FileType: v.FileType, Url: v.FileUrl}
+		if g.cfg.ResultUrlMode == resultUrlModeProxy {
+			output.Url = g.fileUrl(r, record.TaskId, i, expires)
+		}
+		outputs = append(outputs, output)
+	}
+	out["outputs"] = outputs
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (g *gateway) sign(taskId string, index int, expires int64) string {
+	mac := hmac.New(sha256.New, g.signKey)
+	fmt.Fprintf(mac, "%s/%d/%d", taskId, index, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// fileUrl 生成代理下载链接, 链接本身即授权, 不需要团队 token
+func (g *gateway) fileUrl(r *http.Request, taskId string, index int, expires int64) string {
+	base := strings.TrimSuffix(g.cfg.PublicUrl, "/")
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return fmt.Sprintf("%s/v1/files/%s/%d?expires=%d&sig=%s", base, taskId, index, expires, g.sign(taskId, index, expires))
+}
+
+func (g *gateway) handleFile(w http.ResponseWriter, r *http.Request) {
+	taskId := r.PathValue("taskId")
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil || index < 0 {
+		g.writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+	expires, _ := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	sig := r.URL.Query().Get("sig")
+	if !hmac.Equal([]byte(sig), []byte(g.sign(taskId, index, expires))) {
+		g.writeError(w, http.StatusForbidden, "invalid signature")
+		return
+	}
+	if time.Now().Unix() > expires {
+		g.writeError(w, http.StatusForbidden, "link expired")
+		return
+	}
+	res, err := g.client.GetTaskResult(r.Context(), taskId)
+	if err != nil {
+		g.writeUpstreamError(w, "GetTaskResult", err)
+		return
+	}
+	if index >= len(res.SuccessItems) || res.SuccessItems[index] == nil {
+		g.writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+	body, meta, err := utility.OpenURL(r.Context(), res.SuccessItems[index].FileUrl)
+	if err != nil {
+		g.writeUpstreamError(w, "DownloadOutput", err)
+		return
+	}
+	defer body.Close()
+	if meta.ContentType != "" {
+		w.Header().Set("Content-Type", meta.ContentType)
+	}
+	if meta.ContentLength >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(meta.ContentLength, 10))
+	}
+	fileName := meta.FileName
+	if fileName == "" {
+		fileName = path.Base(res.SuccessItems[index].FileUrl)
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": fileName}))
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	io.Copy(w, body)
+}
+
+func (g *gateway) handleCancel(w http.ResponseWriter, r *http.Request) {
+	record, ok := g.ownedRecord(w, r)
+	if !ok {
+		return
+	}
+	res := g.client.CancelTaskWithResult(r.Context(), record.TaskId)
+	if !res.Ok() {
+		g.writeUpstreamError(w, "CancelTask", res.Err)
+		return
+	}
+	// 取消后刷新一次状态以释放并发, 失败时由后台同步处理
+	g.refresh(r.Context(), record)
+	writeJSON(w, http.StatusOK, res)
+}
+
+// handleUpload 接收 multipart 的 file 字段并以流的方式上传, 不落盘
+func (g *gateway) handleUpload(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, g.cfg.MaxUploadBytes)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, "multipart body required: "+err.Error())
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			g.writeError(w, http.StatusBadRequest, "file field is required")
+			return
+		}
+		if err != nil {
+			g.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		res, err := g.client.UploadResourceFromStream(r.Context(), &runninghub_client_utils.UploadStreamInput{
+			FileName:    part.FileName(),
+			ContentType: part.Header.Get("Content-Type"),
+			Reader:      part,
+			Size:        -1,
+		})
+		part.Close()
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				g.writeError(w, http.StatusRequestEntityTooLarge, "file too large")
+				return
+			}
+			g.writeUpstreamError(w, "UploadResource", err)
+			return
+		}
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+}
+
+func (g *gateway) handleWorkflowInputs(w http.ResponseWriter, r *http.Request) {
+	team := teamFromContext(r.Context())
+	workflowId := r.PathValue("workflowId")
+	if len(team.cfg.Workflows) > 0 {
+		allowed := false
+		for _, v := range team.cfg.Workflows {
+			allowed = allowed || v == workflowId
+		}
+		if !allowed {
+			g.writeError(w, http.StatusForbidden, "workflow not allowed")
+			return
+		}
+	}
+	workflow, err := g.client.GetWorkflowJSON(r.Context(), workflowId)
+	if err != nil {
+		g.writeUpstreamError(w, "GetWorkflowJSON", err)
+		return
+	}
+	if workflow.Code != 0 {
+		g.writeUpstreamError(w, "GetWorkflowJSON", &runninghub_client_utils.ApiError{Op: "GetWorkflowJSON", Code: workflow.Code, Msg: workflow.Msg})
+		return
+	}
+	pictureInputs, err := g.client.ParseWorkflowPictureInputNode(r.Context(), workflowId)
+	if err != nil {
+		g.writeUpstreamError(w, "ParseWorkflowPictureInputNode", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"workflowId":    workflowId,
+		"nodes":         workflow.WorkflowData,
+		"pictureInputs": pictureInputs,
+	})
+}
+
+// reconcile 定时同步执行中任务的状态, 团队不再查询时也能释放并发并累计花费
+func (g *gateway) reconcile(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(g.cfg.ReconcileInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, t := range g.teams {
+			for _, taskId := range t.inflightTasks() {
+				record, ok, err := g.tasks.Get(ctx, taskId)
+				if err != nil || !ok {
+					continue
+				}
+				if _, err = g.refresh(ctx, record); err != nil && ctx.Err() == nil {
+					log.Printf("reconcile task(%s) fail: %v", taskId, err)
+				}
+			}
+		}
+	}
+}
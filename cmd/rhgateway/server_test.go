@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Friday-fighting/runninghub_tools/runninghub_client_utils"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeUpstream 模拟 RunningHub 创建、查询、结果、取消接口与输出文件下载
+type fakeUpstream struct {
+	mu      sync.Mutex
+	url     string
+	done    map[string]bool // 任务 id -> 是否已完成
+	creates int
+}
+
+func (f *fakeUpstream) finish(taskId string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.done[taskId] = true
+}
+
+func (f *fakeUpstream) createCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.creates
+}
+
+func (f *fakeUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if taskId, ok := strings.CutPrefix(r.URL.Path, "/files/"); ok {
+		w.Header().Set("Content-Type", "image/png")
+		io.WriteString(w, "png of "+taskId)
+		return
+	}
+	var body struct {
+		TaskId string `json:"taskId"`
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	write := func(code int, data interface{}) {
+		raw, _ := json.Marshal(data)
+		json.NewEncoder(w).Encode(&runninghub_client_utils.RunningHubResponse{Code: code, Msg: "msg", Data: raw})
+	}
+	done, ok := f.done[body.TaskId]
+	if !ok && r.URL.Path != "/task/openapi/create" {
+		write(807, nil)
+		return
+	}
+	switch r.URL.Path {
+	case "/task/openapi/create":
+		f.creates++
+		taskId := fmt.Sprintf("task-%d", f.creates)
+		f.done[taskId] = false
+		write(0, map[string]string{"taskId": taskId, "taskStatus": "QUEUED"})
+	case "/task/openapi/status":
+		if done {
+			write(0, "SUCCESS")
+		} else {
+			write(0, "RUNNING")
+		}
+	case "/task/openapi/outputs":
+		if !done {
+			write(804, nil)
+			return
+		}
+		write(0, []map[string]string{{"fileUrl": f.url + "/files/" + body.TaskId + ".png", "fileType": "png", "nodeId": "9", "consumeMoney": "0.5"}})
+	case "/task/openapi/cancel":
+		f.done[body.TaskId] = true
+		write(0, nil)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newTestGateway 返回使用测试上游的网关服务
+func newTestGateway(t *testing.T, resultUrlMode string, teams ...*teamConfig) (*gateway, *fakeUpstream, *httptest.Server) {
+	t.Helper()
+	upstream := &fakeUpstream{done: make(map[string]bool)}
+	upstreamServer := httptest.NewServer(upstream)
+	t.Cleanup(upstreamServer.Close)
+	upstream.url = upstreamServer.URL
+	store := runninghub_client_utils.NewMemoryStore()
+	g := &gateway{
+		cfg: &config{ResultUrlMode: resultUrlMode, ResultUrlTTL: 60, MaxUploadBytes: 1 << 20, Teams: teams},
+		client: runninghub_client_utils.NewClient(&runninghub_client_utils.RunningHubClientConfig{
+			ApiKey:     "test-api-key",
+			Host:       strings.TrimPrefix(upstreamServer.URL, "http://"),
+			UseHttpReq: true,
+		}),
+		tasks:   runninghub_client_utils.NewTaskStore(store),
+		budget:  runninghub_client_utils.NewBudget(&runninghub_client_utils.BudgetConfig{DefaultEstimate: runninghub_client_utils.Cost{Money: 1}, Store: store}),
+		teams:   make(map[string]*teamState),
+		signKey: []byte("test-sign-key"),
+		metrics: newMetrics(),
+	}
+	for _, v := range teams {
+		g.teams[v.Name] = newTeamState(v)
+	}
+	server := httptest.NewServer(g.routes())
+	t.Cleanup(server.Close)
+	return g, upstream, server
+}
+
+// doRequest 以团队 token 发起请求, 返回状态码与响应体
+func doRequest(t *testing.T, method, url, token, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+const testSubmitBody = `{"workflowId":"wf","nodeInfoList":[{"nodeId":"6","fieldName":"text","fieldValue":"a cat"}]}`
+
+// submitTask 提交任务并返回任务 id
+func submitTask(t *testing.T, server *httptest.Server, token string) string {
+	t.Helper()
+	status, body := doRequest(t, http.MethodPost, server.URL+"/v1/tasks", token, testSubmitBody)
+	if status != http.StatusOK {
+		t.Fatalf("submit = %d %s", status, body)
+	}
+	var res struct {
+		TaskId string `json:"taskId"`
+	}
+	if err := json.Unmarshal([]byte(body), &res); err != nil || res.TaskId == "" {
+		t.Fatalf("submit response %s: %v", body, err)
+	}
+	return res.TaskId
+}
+
+func TestGatewayAuth(t *testing.T) {
+	_, upstream, server := newTestGateway(t, resultUrlModeProxy, &teamConfig{Name: "a", Token: "token-a"})
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{name: "missing", want: http.StatusUnauthorized},
+		{name: "wrong token", header: "Bearer token-b", want: http.StatusUnauthorized},
+		{name: "token prefix", header: "Bearer token", want: http.StatusUnauthorized},
+		{name: "not bearer", header: "token-a", want: http.StatusUnauthorized},
+		{name: "valid", header: "Bearer token-a", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/tasks", strings.NewReader(testSubmitBody))
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+	// 未授权的请求不会到达 RunningHub
+	if got := upstream.createCount(); got != 1 {
+		t.Fatalf("creates = %d, want 1", got)
+	}
+}
+
+func TestGatewayTaskOwnership(t *testing.T) {
+	_, _, server := newTestGateway(t, resultUrlModeProxy,
+		&teamConfig{Name: "a", Token: "token-a"},
+		&teamConfig{Name: "b", Token: "token-b"},
+	)
+	taskId := submitTask(t, server, "token-a")
+	tests := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/v1/tasks/" + taskId},
+		{http.MethodGet, "/v1/tasks/" + taskId + "/result"},
+		{http.MethodPost, "/v1/tasks/" + taskId + "/cancel"},
+	}
+	for _, tt := range tests {
+		// 其他团队的任务与不存在的任务一样返回 404
+		if status, body := doRequest(t, tt.method, server.URL+tt.path, "token-b", ""); status != http.StatusNotFound {
+			t.Errorf("team b %s %s = %d %s", tt.method, tt.path, status, body)
+		}
+		if status, body := doRequest(t, tt.method, server.URL+tt.path, "token-a", ""); status != http.StatusOK {
+			t.Errorf("team a %s %s = %d %s", tt.method, tt.path, status, body)
+		}
+	}
+	if status, _ := doRequest(t, http.MethodGet, server.URL+"/v1/tasks/task-404", "token-a", ""); status != http.StatusNotFound {
+		t.Errorf("missing task = %d", status)
+	}
+}
+
+func TestGatewaySubmitRejected(t *testing.T) {
+	_, upstream, server := newTestGateway(t, resultUrlModeProxy,
+		&teamConfig{Name: "a", Token: "token-a", Workflows: []string{"other"}},
+		&teamConfig{Name: "b", Token: "token-b", MaxConcurrent: 1},
+		&teamConfig{Name: "c", Token: "token-c", AllowWorkflowOverride: true, InstanceTypes: []string{"plus"}},
+	)
+	override := `{"workflowId":"wf","workflow":"{\"3\":{\"class_type\":\"KSampler\",\"inputs\":{}}}"}`
+	plus := `{"workflowId":"wf","instanceType":"plus","nodeInfoList":[{"nodeId":"6","fieldName":"text","fieldValue":"a cat"}]}`
+	tests := []struct {
+		name  string
+		token string
+		body  string
+		want  int
+	}{
+		{name: "workflow not allowed", token: "token-a", body: testSubmitBody, want: http.StatusForbidden},
+		{name: "override not allowed", token: "token-b", body: override, want: http.StatusForbidden},
+		{name: "override allowed", token: "token-c", body: override, want: http.StatusOK},
+		{name: "within concurrency", token: "token-b", body: testSubmitBody, want: http.StatusOK},
+		{name: "over concurrency", token: "token-b", body: testSubmitBody, want: http.StatusTooManyRequests},
+		{name: "empty workflow", token: "token-c", body: `{}`, want: http.StatusBadRequest},
+		// 参数错误不会到达 RunningHub, 返回 400 而不是 502
+		{name: "empty node info list", token: "token-c", body: `{"workflowId":"wf"}`, want: http.StatusBadRequest},
+		{name: "node info without field", token: "token-c", body: `{"workflowId":"wf","nodeInfoList":[{"nodeId":"6"}]}`, want: http.StatusBadRequest},
+		{name: "workflow is not an object", token: "token-c", body: `{"workflowId":"wf","workflow":"[1]"}`, want: http.StatusBadRequest},
+		{name: "unknown instance type", token: "token-c", body: `{"workflowId":"wf","instanceType":"huge","workflow":"{}"}`, want: http.StatusBadRequest},
+		{name: "plus not allowed", token: "token-a", body: strings.Replace(plus, `"wf"`, `"other"`, 1), want: http.StatusForbidden},
+		{name: "plus allowed", token: "token-c", body: plus, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		if status, body := doRequest(t, http.MethodPost, server.URL+"/v1/tasks", tt.token, tt.body); status != tt.want {
+			t.Errorf("%s: status = %d %s, want %d", tt.name, status, body, tt.want)
+		}
+	}
+	if got := upstream.createCount(); got != 3 {
+		t.Fatalf("creates = %d, want 3", got)
+	}
+}
+
+func TestGatewayResultLinks(t *testing.T) {
+	g, upstream, server := newTestGateway(t, resultUrlModeProxy, &teamConfig{Name: "a", Token: "token-a"})
+	taskId := submitTask(t, server, "token-a")
+	upstream.finish(taskId)
+	status, body := doRequest(t, http.MethodGet, server.URL+"/v1/tasks/"+taskId+"/result", "token-a", "")
+	if status != http.StatusOK {
+		t.Fatalf("result = %d %s", status, body)
+	}
+	var res struct {
+		Outputs []*resultOutput `json:"outputs"`
+	}
+	if err := json.Unmarshal([]byte(body), &res); err != nil || len(res.Outputs) != 1 {
+		t.Fatalf("result %s: %v", body, err)
+	}
+	link := res.Outputs[0].Url
+	if !strings.HasPrefix(link, server.URL+"/v1/files/"+taskId+"/0?") {
+		t.Fatalf("link = %s", link)
+	}
+
+	expired := g.fileUrl(httptest.NewRequest(http.MethodGet, server.URL, nil), taskId, 0, time.Now().Add(-time.Second).Unix())
+	tests := []struct {
+		name string
+		url  string
+		want int
+	}{
+		// 链接本身即授权, 不需要 token
+		{name: "valid", url: link, want: http.StatusOK},
+		{name: "tampered signature", url: link[:len(link)-1] + "x", want: http.StatusForbidden},
+		{name: "other index", url: strings.Replace(link, "/0?", "/1?", 1), want: http.StatusForbidden},
+		{name: "other task", url: strings.Replace(link, taskId, "task-999", 1), want: http.StatusForbidden},
+		{name: "extended expiry", url: strings.Replace(link, "expires=", "expires=9", 1), want: http.StatusForbidden},
+		{name: "expired", url: expired, want: http.StatusForbidden},
+		{name: "no signature", url: server.URL + "/v1/files/" + taskId + "/0", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		status, body := doRequest(t, http.MethodGet, tt.url, "", "")
+		if status != tt.want {
+			t.Errorf("%s: status = %d %s, want %d", tt.name, status, body, tt.want)
+		}
+		if tt.want == http.StatusOK && body != "png of "+taskId+".png" {
+			t.Errorf("%s: body = %q", tt.name, body)
+		}
+	}
+	if status, body = doRequest(t, http.MethodGet, expired, "", ""); !strings.Contains(body, "link expired") {
+		t.Errorf("expired link = %d %s", status, body)
+	}
+
+	// direct 模式直接返回平台链接
+	g.cfg.ResultUrlMode = resultUrlModeDirect
+	_, body = doRequest(t, http.MethodGet, server.URL+"/v1/tasks/"+taskId+"/result", "token-a", "")
+	if !strings.Contains(body, upstream.url+"/files/"+taskId+".png") {
+		t.Errorf("direct result = %s", body)
+	}
+}
+
+func TestGatewayFinishOnce(t *testing.T) {
+	g, upstream, server := newTestGateway(t, resultUrlModeProxy, &teamConfig{Name: "a", Token: "token-a", DailyMoney: 10})
+	taskId := submitTask(t, server, "token-a")
+	if u := g.teams["a"].usage(); u.Inflight != 1 || u.Reserved.Money != 1 {
+		t.Fatalf("usage after submit = %+v", u)
+	}
+	upstream.finish(taskId)
+	// 查询状态、结果与后台同步并发结算同一任务
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := "/v1/tasks/" + taskId
+			if i%2 == 0 {
+				path += "/result"
+			}
+			if status, body := doRequest(t, http.MethodGet, server.URL+path, "token-a", ""); status != http.StatusOK {
+				t.Errorf("GET %s = %d %s", path, status, body)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if u := g.teams["a"].usage(); u.Inflight != 0 || u.DayCost.Money != 0.5 || u.Reserved.Money != 0 {
+		t.Fatalf("usage after finish = %+v", u)
+	}
+	_, metrics := doRequest(t, http.MethodGet, server.URL+"/metrics", "", "")
+	if !strings.Contains(metrics, `rhgateway_tasks_finished_total{team="a",status="SUCCESS"} 1`+"\n") {
+		t.Fatalf("metrics:\n%s", metrics)
+	}
+}
+
+// failingStore 写入任务记录失败的 Store
+type failingStore struct {
+	runninghub_client_utils.Store
+}
+
+func (s failingStore) Set(ctx context.Context, key string, value []byte) error {
+	return errors.New("disk full")
+}
+
+func TestGatewaySubmitSaveFail(t *testing.T) {
+	g, upstream, server := newTestGateway(t, resultUrlModeProxy, &teamConfig{Name: "a", Token: "token-a", MaxConcurrent: 1})
+	g.tasks = runninghub_client_utils.NewTaskStore(failingStore{runninghub_client_utils.NewMemoryStore()})
+	if status, body := doRequest(t, http.MethodPost, server.URL+"/v1/tasks", "token-a", testSubmitBody); status != http.StatusInternalServerError {
+		t.Fatalf("submit = %d %s", status, body)
+	}
+	// 没有记录的任务被取消, 不占用团队并发与预估额度
+	upstream.mu.Lock()
+	cancelled := upstream.done["task-1"]
+	upstream.mu.Unlock()
+	if !cancelled {
+		t.Fatal("task without record was not cancelled")
+	}
+	if u := g.teams["a"].usage(); u.Inflight != 0 || u.Reserved != (runninghub_client_utils.Cost{}) {
+		t.Fatalf("usage = %+v", u)
+	}
+}
+
+func TestGatewayRefreshKeepsTerminalRecord(t *testing.T) {
+	g, upstream, server := newTestGateway(t, resultUrlModeProxy, &teamConfig{Name: "a", Token: "token-a"})
+	ctx := context.Background()
+	taskId := submitTask(t, server, "token-a")
+	stale, _, err := g.tasks.Get(ctx, taskId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream.finish(taskId)
+	if status, body := doRequest(t, http.MethodGet, server.URL+"/v1/tasks/"+taskId, "token-a", ""); status != http.StatusOK {
+		t.Fatalf("status = %d %s", status, body)
+	}
+	// 之前读到的旧记录与上游的过期状态不能覆盖已结束的记录
+	upstream.mu.Lock()
+	upstream.done[taskId] = false
+	upstream.mu.Unlock()
+	if _, err = g.refresh(ctx, stale); err != nil {
+		t.Fatal(err)
+	}
+	record, _, err := g.tasks.Get(ctx, taskId)
+	if err != nil || record.Status != runninghub_client_utils.TaskStatusSuccess || record.Cost.Money != 0.5 {
+		t.Fatalf("record = %+v, %v", record, err)
+	}
+	if stale.Status != runninghub_client_utils.TaskStatusSuccess {
+		t.Fatalf("refreshed record status = %s", stale.Status)
+	}
+}
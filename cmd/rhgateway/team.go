@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"github.com/Friday-fighting/runninghub_tools/runninghub_client_utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+const teamTagPrefix = "team:"
+
+const (
+	rejectReasonConcurrency  = "concurrency"
+	rejectReasonDailyTasks   = "daily_tasks"
+	rejectReasonDailyMoney   = "daily_money"
+	rejectReasonDailyCoins   = "daily_coins"
+	rejectReasonWorkflow     = "workflow"
+	rejectReasonInstanceType = "instance_type"
+)
+
+// teamState 团队的执行中任务与当日用量
+type teamState struct {
+	cfg *teamConfig
+
+	mu          sync.Mutex
+	inflight    map[string]runninghub_client_utils.Cost // 执行中任务 -> 预估花费
+	pending     int                                     // 已通过检查、正在提交的任务数
+	pendingCost runninghub_client_utils.Cost            // 正在提交的任务的预估花费
+	day         string
+	dayTasks    int
+	dayCost     runninghub_client_utils.Cost
+}
+
+func newTeamState(cfg *teamConfig) *teamState {
+	return &teamState{
+		cfg:      cfg,
+		inflight: make(map[string]runninghub_client_utils.Cost),
+		day:      time.Now().Format("2006-01-02"),
+	}
+}
+
+func teamTag(name string) string {
+	return teamTagPrefix + name
+}
+
+// teamOfRecord 从任务记录的标签中取出所属团队
+func teamOfRecord(record *runninghub_client_utils.TaskRecord) string {
+	for _, v := range record.Tags {
+		if strings.HasPrefix(v, teamTagPrefix) {
+			return strings.TrimPrefix(v, teamTagPrefix)
+		}
+	}
+	return ""
+}
+
+// rollLocked 跨天时重置当日用量
+func (t *teamState) rollLocked() {
+	if day := time.Now().Format("2006-01-02"); day != t.day {
+		t.day, t.dayTasks, t.dayCost = day, 0, runninghub_client_utils.Cost{}
+	}
+}
+
+// reserve 检查配额与并发并占用一个提交名额及预估花费, 拒绝时返回原因
+// override 表示请求携带了完整的工作流内容, 此时白名单无法约束实际执行的图, 需团队显式允许;
+// 非默认机型的花费高于按工作流历史预估的额度, 同样需团队显式允许。当日花费按已结束任务的实际花费加执行中、提交中任务的预估花费计算, 避免并发提交同时通过检查
+func (t *teamState) reserve(workflowId string, instanceType string, override bool, estimate runninghub_client_utils.Cost) (reason string, ok bool) {
+	if override && !t.cfg.AllowWorkflowOverride {
+		return rejectReasonWorkflow, false
+	}
+	if instanceType != runninghub_client_utils.InstanceTypeDefault {
+		allowed := false
+		for _, v := range t.cfg.InstanceTypes {
+			if v == instanceType {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return rejectReasonInstanceType, false
+		}
+	}
+	if len(t.cfg.Workflows) > 0 {
+		allowed := false
+		for _, v := range t.cfg.Workflows {
+			if v == workflowId {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return rejectReasonWorkflow, false
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollLocked()
+	if t.cfg.MaxConcurrent > 0 && len(t.inflight)+t.pending >= t.cfg.MaxConcurrent {
+		return rejectReasonConcurrency, false
+	}
+	if t.cfg.DailyTasks > 0 && t.dayTasks+t.pending >= t.cfg.DailyTasks {
+		return rejectReasonDailyTasks, false
+	}
+	projected := t.dayCost.Add(t.pendingCost).Add(estimate)
+	for _, v := range t.inflight {
+		projected = projected.Add(v)
+	}
+	if t.cfg.DailyMoney > 0 && (t.dayCost.Money >= t.cfg.DailyMoney || projected.Money > t.cfg.DailyMoney) {
+		return rejectReasonDailyMoney, false
+	}
+	if t.cfg.DailyCoins > 0 && (t.dayCost.Coins >= t.cfg.DailyCoins || projected.Coins > t.cfg.DailyCoins) {
+		return rejectReasonDailyCoins, false
+	}
+	t.pending++
+	t.pendingCost = t.pendingCost.Add(estimate)
+	return "", true
+}
+
+// release 提交结束后释放名额, taskId 为空表示提交失败; 提交成功时预估花费转为执行中任务的占用
+func (t *teamState) release(taskId string, estimate runninghub_client_utils.Cost) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollLocked()
+	t.pending--
+	t.pendingCost = t.pendingCost.Add(runninghub_client_utils.Cost{Money: -estimate.Money, Coins: -estimate.Coins})
+	if t.pending == 0 {
+		// 消除浮点累计误差
+		t.pendingCost = runninghub_client_utils.Cost{}
+	}
+	if taskId != "" {
+		t.inflight[taskId] = estimate
+		t.dayTasks++
+	}
+}
+
+// finish 任务结束后移出执行中, 以实际花费替换预估花费; 任务不在执行中(已被其他请求结算)时不重复累计并返回 false
+func (t *teamState) finish(taskId string, createdAt time.Time, cost runninghub_client_utils.Cost) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollLocked()
+	if _, ok := t.inflight[taskId]; !ok {
+		return false
+	}
+	delete(t.inflight, taskId)
+	if createdAt.Format("2006-01-02") == t.day {
+		t.dayCost = t.dayCost.Add(cost)
+	}
+	return true
+}
+
+func (t *teamState) inflightTasks() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	res := make([]string, 0, len(t.inflight))
+	for taskId := range t.inflight {
+		res = append(res, taskId)
+	}
+	return res
+}
+
+type teamUsage struct {
+	Inflight int
+	DayTasks int
+	DayCost  runninghub_client_utils.Cost
+	Reserved runninghub_client_utils.Cost // 执行中与提交中任务的预估花费
+}
+
+func (t *teamState) usage() teamUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rollLocked()
+	reserved := t.pendingCost
+	for _, v := range t.inflight {
+		reserved = reserved.Add(v)
+	}
+	return teamUsage{Inflight: len(t.inflight), DayTasks: t.dayTasks, DayCost: t.dayCost, Reserved: reserved}
+}
+
+// restoreTeams 从任务记录恢复执行中任务及其预估花费与当日用量
+func restoreTeams(ctx context.Context, store *runninghub_client_utils.TaskStore, budget *runninghub_client_utils.Budget, teams map[string]*teamState) error {
+	today := time.Now().Format("2006-01-02")
+	var estimateErr error
+	err := store.Scan(ctx, func(record *runninghub_client_utils.TaskRecord) bool {
+		t, ok := teams[teamOfRecord(record)]
+		if !ok {
+			return true
+		}
+		if !record.Status.IsTerminal() {
+			t.inflight[record.TaskId], estimateErr = budget.Estimate(ctx, record.WorkflowId)
+			if estimateErr != nil {
+				return false
+			}
+		}
+		if record.CreatedAt.Format("2006-01-02") == today {
+			t.dayTasks++
+			t.dayCost = t.dayCost.Add(record.Cost)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return estimateErr
+}
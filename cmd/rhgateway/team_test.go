@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Friday-fighting/runninghub_tools/runninghub_client_utils"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTeamStateReserve(t *testing.T) {
+	estimate := runninghub_client_utils.Cost{Money: 1, Coins: 100}
+	tests := []struct {
+		name         string
+		cfg          teamConfig
+		instanceType string
+		override     bool
+		// 依次提交的任务数, 之后的提交应以 wantReason 拒绝
+		accepted   int
+		wantReason string
+	}{
+		{name: "concurrency", cfg: teamConfig{MaxConcurrent: 2}, accepted: 2, wantReason: rejectReasonConcurrency},
+		{name: "daily tasks", cfg: teamConfig{DailyTasks: 3}, accepted: 3, wantReason: rejectReasonDailyTasks},
+		{name: "daily money counts estimates", cfg: teamConfig{DailyMoney: 2.5}, accepted: 2, wantReason: rejectReasonDailyMoney},
+		{name: "daily coins counts estimates", cfg: teamConfig{DailyCoins: 300}, accepted: 3, wantReason: rejectReasonDailyCoins},
+		{name: "workflow whitelist", cfg: teamConfig{Workflows: []string{"other"}}, wantReason: rejectReasonWorkflow},
+		{name: "workflow override not allowed", cfg: teamConfig{}, override: true, wantReason: rejectReasonWorkflow},
+		{name: "workflow override allowed", cfg: teamConfig{AllowWorkflowOverride: true, MaxConcurrent: 1}, override: true, accepted: 1, wantReason: rejectReasonConcurrency},
+		{name: "plus instance not allowed", cfg: teamConfig{}, instanceType: "plus", wantReason: rejectReasonInstanceType},
+		{name: "plus instance allowed", cfg: teamConfig{InstanceTypes: []string{"plus"}, MaxConcurrent: 1}, instanceType: "plus", accepted: 1, wantReason: rejectReasonConcurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			team := newTeamState(&tt.cfg)
+			for i := 0; i < tt.accepted; i++ {
+				if reason, ok := team.reserve("wf", tt.instanceType, tt.override, estimate); !ok {
+					t.Fatalf("task %d rejected: %s", i, reason)
+				}
+				team.release(fmt.Sprintf("task-%d", i), estimate)
+			}
+			if reason, ok := team.reserve("wf", tt.instanceType, tt.override, estimate); ok || reason != tt.wantReason {
+				t.Fatalf("reserve() = %q, %v, want %q", reason, ok, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestTeamStateFinish(t *testing.T) {
+	team := newTeamState(&teamConfig{DailyMoney: 3})
+	estimate := runninghub_client_utils.Cost{Money: 1}
+	// 并发提交时正在提交的任务同样占用预估花费
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted []string
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, ok := team.reserve("wf", "", false, estimate); !ok {
+				return
+			}
+			taskId := fmt.Sprintf("task-%d", i)
+			team.release(taskId, estimate)
+			mu.Lock()
+			accepted = append(accepted, taskId)
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	if len(accepted) != 3 {
+		t.Fatalf("accepted %d tasks, want 3", len(accepted))
+	}
+	if u := team.usage(); u.Reserved.Money != 3 || u.Inflight != 3 {
+		t.Fatalf("usage = %+v", u)
+	}
+	// 实际花费低于预估时释放额度, 重复结算不重复累计
+	if !team.finish(accepted[0], time.Now(), runninghub_client_utils.Cost{Money: 0.5}) {
+		t.Fatal("finish() = false")
+	}
+	if team.finish(accepted[0], time.Now(), runninghub_client_utils.Cost{Money: 0.5}) {
+		t.Fatal("second finish() = true")
+	}
+	if u := team.usage(); u.DayCost.Money != 0.5 || u.Reserved.Money != 2 || u.Inflight != 2 {
+		t.Fatalf("usage after finish = %+v", u)
+	}
+	if _, ok := team.reserve("wf", "", false, runninghub_client_utils.Cost{Money: 0.5}); !ok {
+		t.Fatal("reserve() within remaining budget rejected")
+	}
+	team.release("", runninghub_client_utils.Cost{Money: 0.5})
+	if reason, ok := team.reserve("wf", "", false, estimate); ok || reason != rejectReasonDailyMoney {
+		t.Fatalf("reserve() = %q, %v, want %q", reason, ok, rejectReasonDailyMoney)
+	}
+}
@@ -2,7 +2,11 @@ module github.com/Friday-fighting/runninghub_tools
 
 go 1.24.2
 
-require github.com/gogf/gf/v2 v2.9.3
+require (
+	github.com/gogf/gf/v2 v2.9.3
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/image v0.32.0
+)
 
 require (
 	github.com/BurntSushi/toml v1.5.0 // indirect
@@ -30,6 +34,6 @@ require (
 	go.opentelemetry.io/otel/trace v1.37.0 // indirect
 	golang.org/x/net v0.43.0 // indirect
 	golang.org/x/sys v0.35.0 // indirect
-	golang.org/x/text v0.28.0 // indirect
+	golang.org/x/text v0.30.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,313 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"fmt"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	BalanceKindMoney = "money"
+	BalanceKindCoins = "coins"
+)
+
+// Money 定点金额, 以 0.0001 为单位, 余额按字符串解析, 避免浮点误差
+type Money int64
+
+const (
+	moneyScale    = 10000
+	moneyDecimals = 4
+)
+
+// ParseMoney 解析十进制金额字符串, 最多 4 位小数, 空字符串为 0
+func ParseMoney(s string) (Money, error) {
+	value := strings.TrimSpace(s)
+	if value == "" {
+		return 0, nil
+	}
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+	intPart, fracPart, _ := strings.Cut(value, ".")
+	fracPart = strings.TrimRight(fracPart, "0")
+	if intPart == "" && fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, gerror.Newf("invalid money(%s)", s)
+	}
+	if len(fracPart) > moneyDecimals {
+		return 0, gerror.Newf("money(%s) has more than %d decimals", s, moneyDecimals)
+	}
+	var i, f int64
+	var err error
+	if intPart != "" {
+		if i, err = strconv.ParseInt(intPart, 10, 64); err != nil || i > math.MaxInt64/moneyScale-1 {
+			return 0, gerror.Newf("money(%s) out of range", s)
+		}
+	}
+	if fracPart != "" {
+		f, _ = strconv.ParseInt(fracPart+strings.Repeat("0", moneyDecimals-len(fracPart)), 10, 64)
+	}
+	m := Money(i*moneyScale + f)
+	if negative {
+		m = -m
+	}
+	return m, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Float64 转为浮点数, 用于与 Cost 等估算值比较
+func (m Money) Float64() float64 {
+	return float64(m) / moneyScale
+}
+
+func (m Money) String() string {
+	sign := ""
+	if m < 0 {
+		sign, m = "-", -m
+	}
+	res := fmt.Sprintf("%s%d.%04d", sign, m/moneyScale, m%moneyScale)
+	return strings.TrimSuffix(strings.TrimRight(res, "0"), ".")
+}
+
+func (m Money) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalText(text []byte) (err error) {
+	*m, err = ParseMoney(string(text))
+	return err
+}
+
+// BalanceSnapshot 解析后的账户余额快照
+type BalanceSnapshot struct {
+	RemainMoney       Money     `json:"remainMoney"`
+	RemainCoins       int64     `json:"remainCoins"`
+	CurrentTaskCounts int       `json:"currentTaskCounts"`
+	Currency          string    `json:"currency"`
+	ApiType           string    `json:"apiType"`
+	At                time.Time `json:"at"`
+}
+
+// ParseBalanceSnapshot 将 GetAccountRes 中的字符串字段解析为数值, 格式错误时返回错误
+func ParseBalanceSnapshot(res *GetAccountRes) (*BalanceSnapshot, error) {
+	if res == nil {
+		return nil, gerror.New("account info cannot be nil")
+	}
+	money, err := ParseMoney(res.RemainMoney)
+	if err != nil {
+		return nil, gerror.Wrap(err, "parse remainMoney fail")
+	}
+	coins, err := parseBalanceInt(res.RemainCoins)
+	if err != nil {
+		return nil, gerror.Wrapf(err, "parse remainCoins(%s) fail", res.RemainCoins)
+	}
+	taskCounts, err := parseBalanceInt(res.CurrentTaskCounts)
+	if err != nil {
+		return nil, gerror.Wrapf(err, "parse currentTaskCounts(%s) fail", res.CurrentTaskCounts)
+	}
+	return &BalanceSnapshot{
+		RemainMoney:       money,
+		RemainCoins:       coins,
+		CurrentTaskCounts: int(taskCounts),
+		Currency:          res.Currency,
+		ApiType:           res.ApiType,
+		At:                time.Now(),
+	}, nil
+}
+
+func parseBalanceInt(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+type BalanceWatcherConfig struct {
+	Client      *RunningHubClient
+	Interval    time.Duration // 轮询间隔, 默认 1 分钟
+	LowMoney    Money         // RemainMoney 低于该值时告警, 0 不检查
+	LowCoins    int64         // RemainCoins 低于该值时告警, 0 不检查
+	MaxBurnRate Cost          // 每小时消耗上限, 超出时告警, 为 0 的项不检查
+	BurnWindow  time.Duration // 计算消耗速度的时间窗口, 默认 1 小时; 余额增加(充值)后重新开始计算
+	Budget      *Budget       // 不为空时每次轮询后同步余额到预算守卫
+
+	OnSnapshot   func(snapshot *BalanceSnapshot)
+	OnLowBalance func(snapshot *BalanceSnapshot, kind string) // kind: money / coins, 仅在从阈值以上降到阈值以下时触发
+	OnBurnRate   func(snapshot *BalanceSnapshot, ratePerHour Cost)
+	OnError      func(err error)
+}
+
+// BalanceWatcher 定时轮询 GetAccountInfo, 在余额低于阈值或消耗过快时回调
+type BalanceWatcher struct {
+	cfg     *BalanceWatcherConfig
+	mu      sync.RWMutex
+	latest  *BalanceSnapshot
+	history []*BalanceSnapshot // 时间窗口内的快照, 用于计算消耗速度
+	low     map[string]bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+	now     func() time.Time
+}
+
+func NewBalanceWatcher(in *BalanceWatcherConfig) *BalanceWatcher {
+	if in.Interval <= 0 {
+		in.Interval = time.Minute
+	}
+	if in.BurnWindow <= 0 {
+		in.BurnWindow = time.Hour
+	}
+	return &BalanceWatcher{
+		cfg: in,
+		low: make(map[string]bool),
+		now: time.Now,
+	}
+}
+
+// Latest 返回最近一次的余额快照, 尚未轮询时为 nil
+func (w *BalanceWatcher) Latest() *BalanceSnapshot {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.latest
+}
+
+// Start 在后台开始轮询, 重复调用无效
+func (w *BalanceWatcher) Start(ctx context.Context) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return
+	}
+	ctx, w.cancel = context.WithCancel(ctx)
+	w.done = make(chan struct{})
+	go func() {
+		defer close(w.done)
+		w.Run(ctx)
+	}()
+}
+
+// Stop 停止后台轮询并等待退出
+func (w *BalanceWatcher) Stop() {
+	w.mu.Lock()
+	cancel, done := w.cancel, w.done
+	w.cancel, w.done = nil, nil
+	w.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// Run 阻塞轮询直到 ctx 取消
+func (w *BalanceWatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.Poll(ctx); err != nil && w.cfg.OnError != nil && ctx.Err() == nil {
+			w.cfg.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll 立即获取一次余额并触发回调
+func (w *BalanceWatcher) Poll(ctx context.Context) (snapshot *BalanceSnapshot, err error) {
+	if w.cfg.Client == nil {
+		return nil, gerror.New("balance watcher client cannot be nil")
+	}
+	res, err := w.cfg.Client.GetAccountInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err = ParseBalanceSnapshot(res)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.At = w.now()
+
+	w.mu.Lock()
+	w.latest = snapshot
+	var lowKinds []string
+	for _, v := range []struct {
+		kind      string
+		value     int64
+		threshold int64
+	}{
+		{BalanceKindMoney, int64(snapshot.RemainMoney), int64(w.cfg.LowMoney)},
+		{BalanceKindCoins, snapshot.RemainCoins, w.cfg.LowCoins},
+	} {
+		if v.threshold <= 0 {
+			continue
+		}
+		isLow := v.value < v.threshold
+		if isLow && !w.low[v.kind] {
+			lowKinds = append(lowKinds, v.kind)
+		}
+		w.low[v.kind] = isLow
+	}
+	rate, hasRate := w.burnRateLocked(snapshot)
+	w.mu.Unlock()
+
+	if w.cfg.Budget != nil {
+		w.cfg.Budget.SetBalance(snapshot.cost())
+	}
+	if w.cfg.OnSnapshot != nil {
+		w.cfg.OnSnapshot(snapshot)
+	}
+	if w.cfg.OnLowBalance != nil {
+		for _, kind := range lowKinds {
+			w.cfg.OnLowBalance(snapshot, kind)
+		}
+	}
+	if hasRate && w.cfg.OnBurnRate != nil && rate.exceeds(w.cfg.MaxBurnRate) {
+		w.cfg.OnBurnRate(snapshot, rate)
+	}
+	return snapshot, nil
+}
+
+// burnRateLocked 记录快照并按时间窗口内最早的快照计算每小时消耗; 余额增加视为充值, 丢弃之前的快照
+func (w *BalanceWatcher) burnRateLocked(snapshot *BalanceSnapshot) (rate Cost, ok bool) {
+	if n := len(w.history); n > 0 {
+		prev := w.history[n-1]
+		if snapshot.RemainMoney > prev.RemainMoney || snapshot.RemainCoins > prev.RemainCoins {
+			w.history = w.history[:0]
+		}
+	}
+	w.history = append(w.history, snapshot)
+	cutoff := snapshot.At.Add(-w.cfg.BurnWindow)
+	i := 0
+	// 保留一个不晚于窗口起点的快照, 使计算跨度覆盖整个窗口
+	for i+1 < len(w.history) && !w.history[i+1].At.After(cutoff) {
+		i++
+	}
+	w.history = append(w.history[:0], w.history[i:]...)
+	first := w.history[0]
+	hours := snapshot.At.Sub(first.At).Hours()
+	if hours <= 0 {
+		return rate, false
+	}
+	return Cost{
+		Money: (first.RemainMoney - snapshot.RemainMoney).Float64() / hours,
+		Coins: float64(first.RemainCoins-snapshot.RemainCoins) / hours,
+	}, true
+}
+
+// cost 以 Cost 表示余额
+func (s *BalanceSnapshot) cost() Cost {
+	return Cost{Money: s.RemainMoney.Float64(), Coins: float64(s.RemainCoins)}
+}
@@ -0,0 +1,141 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		str     string
+		wantErr bool
+	}{
+		{in: "", want: 0, str: "0"},
+		{in: "10.5", want: 105000, str: "10.5"},
+		{in: " 0.1 ", want: 1000, str: "0.1"},
+		{in: "0.0001", want: 1, str: "0.0001"},
+		{in: ".25", want: 2500, str: "0.25"},
+		{in: "3.", want: 30000, str: "3"},
+		{in: "-1.2300", want: -12300, str: "-1.23"},
+		{in: "1.000000", want: 10000, str: "1"},
+		{in: "1.00001", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "1.+5", wantErr: true},
+		{in: "+1", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) = %d, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want || got.String() != tt.str {
+			t.Errorf("ParseMoney(%q) = %d (%s), %v, want %d (%s)", tt.in, got, got, err, tt.want, tt.str)
+		}
+	}
+	// 0.1 + 0.2 不产生浮点误差
+	a, _ := ParseMoney("0.1")
+	b, _ := ParseMoney("0.2")
+	if c, _ := ParseMoney("0.3"); a+b != c {
+		t.Fatalf("0.1 + 0.2 = %s", a+b)
+	}
+	var snapshot BalanceSnapshot
+	if err := json.Unmarshal([]byte(`{"remainMoney":"12.34"}`), &snapshot); err != nil || snapshot.RemainMoney != 123400 {
+		t.Fatalf("unmarshal = %d, %v", snapshot.RemainMoney, err)
+	}
+	if body, _ := json.Marshal(&BalanceSnapshot{RemainMoney: 123400}); string(body[:22]) != `{"remainMoney":"12.34"` {
+		t.Fatalf("marshal = %s", body)
+	}
+}
+
+func TestParseBalanceSnapshot(t *testing.T) {
+	snapshot, err := ParseBalanceSnapshot(&GetAccountRes{RemainMoney: "10.5", RemainCoins: "300", CurrentTaskCounts: "2"})
+	if err != nil || snapshot.RemainMoney != 105000 || snapshot.RemainCoins != 300 || snapshot.CurrentTaskCounts != 2 {
+		t.Fatalf("ParseBalanceSnapshot() = %+v, %v", snapshot, err)
+	}
+	for _, v := range []*GetAccountRes{
+		{RemainMoney: "ten"},
+		{RemainCoins: "1.5"},
+		{CurrentTaskCounts: "n/a"},
+	} {
+		if _, err = ParseBalanceSnapshot(v); err == nil {
+			t.Errorf("ParseBalanceSnapshot(%+v) expected error", v)
+		}
+	}
+}
+
+func TestBalanceWatcher(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		money = "100"
+		coins = "1000"
+	)
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		writeTestResponse(w, 0, "success", &GetAccountRes{RemainMoney: money, RemainCoins: coins, CurrentTaskCounts: "0"})
+	}))
+	var (
+		lows  []string
+		rates []Cost
+	)
+	watcher := NewBalanceWatcher(&BalanceWatcherConfig{
+		Client:       client,
+		LowMoney:     50 * moneyScale,
+		LowCoins:     500,
+		MaxBurnRate:  Cost{Money: 10},
+		BurnWindow:   time.Hour,
+		OnLowBalance: func(snapshot *BalanceSnapshot, kind string) { lows = append(lows, kind) },
+		OnBurnRate:   func(snapshot *BalanceSnapshot, rate Cost) { rates = append(rates, rate) },
+	})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	watcher.now = func() time.Time { return now }
+	// 每 20 分钟轮询一次
+	steps := []struct {
+		money, coins string
+		wantLows     int
+		wantRates    int
+	}{
+		{"100", "1000", 0, 0},
+		{"90", "1000", 0, 1},  // 20 分钟消耗 10, 每小时 30
+		{"90", "1000", 0, 2},  // 窗口内 40 分钟消耗 10, 每小时 15
+		{"90", "1000", 0, 2},  // 1 小时消耗 10, 未超出
+		{"90", "1000", 0, 2},  // 最早的快照滑出窗口
+		{"200", "1000", 0, 2}, // 充值, 重新计算
+		{"195", "1000", 0, 3}, // 20 分钟消耗 5, 每小时 15
+		{"40", "400", 2, 4},   // 低于两项阈值
+		{"39", "300", 2, 5},   // 仍低于阈值不重复告警
+		{"60", "600", 2, 5},   // 充值后恢复
+		{"45", "600", 3, 6},   // 再次降到阈值以下
+	}
+	for i, step := range steps {
+		mu.Lock()
+		money, coins = step.money, step.coins
+		mu.Unlock()
+		snapshot, err := watcher.Poll(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if watcher.Latest() != snapshot || !snapshot.At.Equal(now) {
+			t.Fatalf("step %d: Latest() = %+v", i, watcher.Latest())
+		}
+		if len(lows) != step.wantLows || len(rates) != step.wantRates {
+			t.Fatalf("step %d: lows = %v, rates = %v", i, lows, rates)
+		}
+		now = now.Add(20 * time.Minute)
+	}
+	if rates[0].Money != 30 || rates[1].Money != 15 {
+		t.Fatalf("burn rates = %+v", rates)
+	}
+}
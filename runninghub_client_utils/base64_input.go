@@ -0,0 +1,72 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/Friday-fighting/runninghub_tools/utility"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+	"io"
+)
+
+// EncodeBase64Input 将资源 base64 编码后填入 base64 类型图片输入节点对应的字段
+func EncodeBase64Input(ctx context.Context, node *WorkflowNodeInfo, src *FileSource, opt *Base64InputOption) (res *NodeInfo, err error) {
+	if node == nil || src == nil {
+		return nil, gerror.New("node and file source cannot be nil")
+	}
+	if node.NodeType != NodeTypeBase64 {
+		return nil, gerror.Newf("node(%s) type is %s, not %s", node.NodeId, node.NodeType, NodeTypeBase64)
+	}
+	if opt == nil {
+		opt = &Base64InputOption{}
+	}
+	r, _, err := openFileSource(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var data []byte
+	if opt.Preprocess != nil {
+		preprocess := opt.Preprocess
+		// 上传限制作用于 base64 字符串, 约为原始大小的 4/3, 压缩目标需按编码后长度换算
+		if maxBytes := int64(base64.StdEncoding.DecodedLen(opt.MaxPayloadBytes)); opt.MaxPayloadBytes > 0 && (preprocess.MaxBytes <= 0 || preprocess.MaxBytes > maxBytes) {
+			copied := *preprocess
+			copied.MaxBytes = maxBytes
+			preprocess = &copied
+		}
+		processed, err := utility.PreprocessImage(ctx, r, preprocess)
+		if err != nil {
+			return nil, gerror.Wrap(err, "preprocess image fail")
+		}
+		data = processed.Data
+	} else if data, err = io.ReadAll(r); err != nil {
+		return nil, err
+	}
+	if n := base64.StdEncoding.EncodedLen(len(data)); opt.MaxPayloadBytes > 0 && n > opt.MaxPayloadBytes {
+		msg := fmt.Sprintf("base64 payload of node(%s) is %d bytes, exceeds %d bytes", node.NodeId, n, opt.MaxPayloadBytes)
+		if opt.Warn != nil {
+			opt.Warn(msg)
+		} else {
+			g.Log().Warning(ctx, msg)
+		}
+	}
+	return &NodeInfo{
+		NodeId:     node.NodeId,
+		FieldName:  node.FieldName,
+		FieldValue: base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// EncodeBase64InputByClassType 按节点 class_type 查找 base64 字段后编码, 适用于 LoadImageFromBase64 等节点
+func EncodeBase64InputByClassType(ctx context.Context, nodeId string, classType string, src *FileSource, opt *Base64InputOption) (res *NodeInfo, err error) {
+	ok, meta := JudgeRunningHubWorkflowNodeIsPictureInputNode(classType)
+	if !ok {
+		return nil, gerror.Newf("class_type(%s) is not a picture input node", classType)
+	}
+	return EncodeBase64Input(ctx, &WorkflowNodeInfo{
+		NodeType:  meta.NodeType,
+		NodeId:    nodeId,
+		FieldName: meta.FieldName,
+	}, src, opt)
+}
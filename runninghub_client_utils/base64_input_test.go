@@ -0,0 +1,63 @@
+package runninghub_client_utils
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand"
+	"testing"
+
+	"github.com/Friday-fighting/runninghub_tools/utility"
+)
+
+func noisePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	rnd := rand.New(rand.NewSource(1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), uint8(rnd.Intn(256)), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestEncodeBase64InputPayloadLimit(t *testing.T) {
+	node := &WorkflowNodeInfo{NodeId: "1", NodeType: NodeTypeBase64, FieldName: "data"}
+	src := &FileSource{UploadMode: 0, Bytes: noisePNG(t, 256, 256)}
+	const limit = 64 << 10
+	var warned []string
+	res, err := EncodeBase64Input(context.Background(), node, src, &Base64InputOption{
+		Preprocess:      &utility.ImagePreprocessOption{Format: utility.ImageFormatJPEG},
+		MaxPayloadBytes: limit,
+		Warn:            func(msg string) { warned = append(warned, msg) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(res.FieldValue); n > limit {
+		t.Fatalf("payload is %d bytes, exceeds %d", n, limit)
+	}
+	if len(warned) > 0 {
+		t.Fatalf("unexpected warning: %v", warned)
+	}
+
+	warned = nil
+	_, err = EncodeBase64Input(context.Background(), node, src, &Base64InputOption{
+		MaxPayloadBytes: len(src.Bytes),
+		Warn:            func(msg string) { warned = append(warned, msg) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 原始大小未超限, 编码后超限
+	if len(warned) != 1 {
+		t.Fatalf("warnings = %v, want 1", warned)
+	}
+}
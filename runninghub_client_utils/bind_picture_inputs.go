@@ -0,0 +1,122 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// BindWorkflowPictureInputs 解析工作流的图片输入节点并绑定资源, 返回可直接用于 CreateTaskReq 的 NodeInfoList
+func (c *RunningHubClient) BindWorkflowPictureInputs(ctx context.Context, workflowId string, inputs []*MediaInput, opt *BindPictureInputsOption) (res []*NodeInfo, err error) {
+	nodes, err := c.ParseWorkflowPictureInputNode(ctx, workflowId)
+	if err != nil {
+		return nil, err
+	}
+	return c.BindPictureInputs(ctx, nodes, inputs, opt)
+}
+
+// BindPictureInputs 将资源绑定到图片输入节点: 指定 Name 的按 _meta.title 或节点 id 匹配, 其余按节点顺序依次绑定;
+// file 节点上传后填入文件名, url 节点填入公网链接, base64 节点填入编码内容
+func (c *RunningHubClient) BindPictureInputs(ctx context.Context, nodes []WorkflowNodeInfo, inputs []*MediaInput, opt *BindPictureInputsOption) (res []*NodeInfo, err error) {
+	if opt == nil {
+		opt = &BindPictureInputsOption{}
+	}
+	bound := make([]*MediaInput, len(nodes))
+	var unnamed []*MediaInput
+	for _, input := range inputs {
+		if input == nil || input.Source == nil {
+			return nil, gerror.New("media input source cannot be nil")
+		}
+		if input.Name == "" {
+			unnamed = append(unnamed, input)
+			continue
+		}
+		idx := findPictureInputNode(nodes, input.Name)
+		if idx < 0 {
+			return nil, gerror.Newf("picture input node(%s) not found", input.Name)
+		}
+		if bound[idx] != nil {
+			return nil, gerror.Newf("picture input node(%s) bound more than once", input.Name)
+		}
+		bound[idx] = input
+	}
+	for i := range bound {
+		if len(unnamed) == 0 {
+			break
+		}
+		if bound[i] == nil {
+			bound[i], unnamed = unnamed[0], unnamed[1:]
+		}
+	}
+	if len(unnamed) > 0 {
+		return nil, gerror.Newf("%d media inputs left without picture input node", len(unnamed))
+	}
+
+	res = make([]*NodeInfo, 0, len(nodes))
+	for i, input := range bound {
+		if input == nil {
+			continue
+		}
+		nodeInfo, err := c.bindPictureInput(ctx, &nodes[i], input.Source, opt)
+		if err != nil {
+			return nil, gerror.Wrapf(err, "bind picture input node(%s) fail", nodes[i].NodeId)
+		}
+		res = append(res, nodeInfo)
+	}
+	return res, nil
+}
+
+func (c *RunningHubClient) bindPictureInput(ctx context.Context, node *WorkflowNodeInfo, src *FileSource, opt *BindPictureInputsOption) (*NodeInfo, error) {
+	res := &NodeInfo{
+		NodeId:    node.NodeId,
+		FieldName: node.FieldName,
+	}
+	switch node.NodeType {
+	case NodeTypeBase64:
+		base64Opt := &Base64InputOption{}
+		if opt.Base64 != nil {
+			*base64Opt = *opt.Base64
+		}
+		if base64Opt.Preprocess == nil {
+			base64Opt.Preprocess = opt.Preprocess
+		}
+		return EncodeBase64Input(ctx, node, src, base64Opt)
+	case NodeTypeUrl:
+		// 链接资源且无需预处理时直接使用原链接, 否则通过 v2 上传获取下载链接
+		if src.UploadMode == UploadModeUrl && opt.Preprocess == nil {
+			res.FieldValue = src.Url
+			return res, nil
+		}
+		uploadRes, err := c.UploadFromSource(ctx, src, &UploadSourceOption{UseV2: true, Preprocess: opt.Preprocess})
+		if err != nil {
+			return nil, err
+		}
+		if uploadRes.DownloadUrl == "" {
+			return nil, gerror.New("upload result has no download url")
+		}
+		res.FieldValue = uploadRes.DownloadUrl
+		return res, nil
+	case NodeTypeFile:
+		uploadRes, err := c.UploadFromSource(ctx, src, &UploadSourceOption{UseV2: opt.UseV2, Preprocess: opt.Preprocess})
+		if err != nil {
+			return nil, err
+		}
+		res.FieldValue = uploadRes.FileName
+		return res, nil
+	default:
+		return nil, gerror.Newf("unsupported node type: %s", node.NodeType)
+	}
+}
+
+func findPictureInputNode(nodes []WorkflowNodeInfo, name string) int {
+	for i, v := range nodes {
+		if v.Title == name {
+			return i
+		}
+	}
+	for i, v := range nodes {
+		if v.NodeId == name {
+			return i
+		}
+	}
+	return -1
+}
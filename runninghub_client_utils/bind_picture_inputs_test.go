@@ -0,0 +1,109 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBindPictureInputs(t *testing.T) {
+	nodes := []WorkflowNodeInfo{
+		{NodeType: NodeTypeFile, NodeId: "1", FieldName: "image", Title: "front"},
+		{NodeType: NodeTypeBase64, NodeId: "2", FieldName: "data", Title: "mask"},
+		{NodeType: NodeTypeUrl, NodeId: "3", FieldName: "url", Title: "back"},
+	}
+	bytesInput := func(name string, fileName string) *MediaInput {
+		return &MediaInput{Name: name, Source: &FileSource{UploadMode: UploadModeBytes, Bytes: []byte(fileName), FileName: fileName}}
+	}
+	b64 := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name        string
+		inputs      []*MediaInput
+		want        []string // nodeId:fieldName=fieldValue
+		wantUploads int
+		wantV2      int
+		wantErr     string
+	}{
+		{
+			name:        "by order",
+			inputs:      []*MediaInput{bytesInput("", "a.png"), bytesInput("", "b.png"), bytesInput("", "c.png")},
+			want:        []string{"1:image=api/a.png", "2:data=" + b64("b.png"), "3:url=https://example.com/c.png"},
+			wantUploads: 2,
+			wantV2:      1,
+		},
+		{
+			name:        "by title and node id",
+			inputs:      []*MediaInput{bytesInput("back", "a.png"), bytesInput("1", "b.png")},
+			want:        []string{"1:image=api/b.png", "3:url=https://example.com/a.png"},
+			wantUploads: 2,
+			wantV2:      1,
+		},
+		{
+			name:        "named first then order",
+			inputs:      []*MediaInput{bytesInput("", "a.png"), bytesInput("front", "b.png")},
+			want:        []string{"1:image=api/b.png", "2:data=" + b64("a.png")},
+			wantUploads: 1,
+		},
+		{
+			name:   "url source on url node is not uploaded",
+			inputs: []*MediaInput{{Name: "back", Source: &FileSource{UploadMode: UploadModeUrl, Url: "https://example.com/remote.png"}}},
+			want:   []string{"3:url=https://example.com/remote.png"},
+		},
+		{name: "unmatched name", inputs: []*MediaInput{bytesInput("side", "a.png")}, wantErr: "picture input node(side) not found"},
+		{name: "bound twice", inputs: []*MediaInput{bytesInput("mask", "a.png"), bytesInput("2", "b.png")}, wantErr: "bound more than once"},
+		{
+			name:    "more inputs than nodes",
+			inputs:  []*MediaInput{bytesInput("", "a.png"), bytesInput("", "b.png"), bytesInput("", "c.png"), bytesInput("", "d.png")},
+			wantErr: "1 media inputs left without picture input node",
+		},
+		{name: "nil source", inputs: []*MediaInput{{Name: "front"}}, wantErr: "media input source cannot be nil"},
+		{name: "empty bytes", inputs: []*MediaInput{{Source: &FileSource{UploadMode: UploadModeBytes}}}, wantErr: "bind picture input node(1) fail"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeUploadServer{}
+			client := newTestClient(t, fake)
+			res, err := client.BindPictureInputs(context.Background(), nodes, tt.inputs, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("BindPictureInputs() err = %v, want %q", err, tt.wantErr)
+				}
+				if got := fake.uploads(); len(got) != 0 {
+					t.Fatalf("uploads = %+v, want none", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, v := range res {
+				got = append(got, fmt.Sprintf("%s:%s=%s", v.NodeId, v.FieldName, v.FieldValue))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("BindPictureInputs() = %v, want %v", got, tt.want)
+			}
+			// url 节点总是通过 v2 上传获取下载链接, file 节点默认使用 v1
+			uploads, v2 := fake.uploads(), 0
+			for _, v := range uploads {
+				if v.V2 {
+					v2++
+				}
+			}
+			if len(uploads) != tt.wantUploads || v2 != tt.wantV2 {
+				t.Fatalf("uploads = %+v, want %d with %d v2", uploads, tt.wantUploads, tt.wantV2)
+			}
+		})
+	}
+}
+
+func TestBindPictureInputsUnsupportedNodeType(t *testing.T) {
+	client := newTestClient(t, &fakeUploadServer{})
+	nodes := []WorkflowNodeInfo{{NodeType: "video", NodeId: "1", FieldName: "video"}}
+	inputs := []*MediaInput{{Source: &FileSource{UploadMode: UploadModeBytes, Bytes: []byte("a")}}}
+	if _, err := client.BindPictureInputs(context.Background(), nodes, inputs, nil); err == nil || !strings.Contains(err.Error(), "unsupported node type: video") {
+		t.Fatalf("BindPictureInputs() err = %v", err)
+	}
+}
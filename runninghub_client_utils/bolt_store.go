@@ -0,0 +1,121 @@
+package runninghub_client_utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"go.etcd.io/bbolt"
+	bbolterrors "go.etcd.io/bbolt/errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var boltStoreBucket = []byte("runninghub")
+
+// BoltStore 基于 bbolt 的嵌入式存储, 单文件、纯 Go, 适合任务记录等持续增长的数据
+type BoltStore struct {
+	db *bbolt.DB
+}
+
+type BoltStoreOption struct {
+	// ReadOnly 只读打开已有的数据库文件, 多个只读进程可以同时打开;
+	// bbolt 的只读打开仍需等待写入进程关闭数据库, 查询运行中服务的数据时需先停止服务或复制一份文件
+	ReadOnly bool
+	Timeout  time.Duration // 等待其他进程释放文件锁的时间, 默认 5s
+}
+
+// NewBoltStore 打开或创建数据库文件, 同一文件同时只能被一个进程打开
+func NewBoltStore(path string) (*BoltStore, error) {
+	return NewBoltStoreWithOption(path, nil)
+}
+
+// NewBoltStoreWithOption 按选项打开数据库文件, 只读时文件必须已存在
+func NewBoltStoreWithOption(path string, opt *BoltStoreOption) (*BoltStore, error) {
+	if opt == nil {
+		opt = &BoltStoreOption{}
+	}
+	timeout := opt.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	if !opt.ReadOnly {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return nil, err
+		}
+	}
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: timeout, ReadOnly: opt.ReadOnly})
+	if errors.Is(err, bbolterrors.ErrTimeout) {
+		return nil, gerror.Wrapf(err, "open %s fail, the database is locked by another process, stop it or query a copy of the file", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opt.ReadOnly {
+		return &BoltStore{db: db}, nil
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltStoreBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{
+		db: db,
+	}, nil
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) Get(ctx context.Context, key string) (value []byte, ok bool, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(boltStoreBucket)
+		if bucket == nil {
+			// 只读打开从未写入过的数据库
+			return nil
+		}
+		if v := bucket.Get([]byte(key)); v != nil {
+			// bbolt 返回的切片仅在事务内有效
+			value, ok = bytes.Clone(v), true
+		}
+		return nil
+	})
+	return value, ok, err
+}
+
+func (s *BoltStore) Set(ctx context.Context, key string, value []byte) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltStoreBucket).Put([]byte(key), value)
+	})
+}
+
+func (s *BoltStore) Delete(ctx context.Context, key string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltStoreBucket).Delete([]byte(key))
+	})
+}
+
+func (s *BoltStore) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) bool) error {
+	return s.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(boltStoreBucket)
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		p := []byte(prefix)
+		for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !fn(string(k), bytes.Clone(v)) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
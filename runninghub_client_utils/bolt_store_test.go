@@ -0,0 +1,76 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"go.etcd.io/bbolt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBoltStoreReadOnly(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db", "history.db")
+	if _, err := NewBoltStoreWithOption(path, &BoltStoreOption{ReadOnly: true}); err == nil {
+		t.Fatal("read-only open of a missing file should fail")
+	}
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Set(ctx, "task:a", []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	// 写入进程未关闭时只读打开等待超时
+	_, err = NewBoltStoreWithOption(path, &BoltStoreOption{ReadOnly: true, Timeout: 50 * time.Millisecond})
+	if err == nil || !strings.Contains(err.Error(), "locked by another process") {
+		t.Fatalf("read-only open while writer is open: %v", err)
+	}
+	if err = store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 多个只读实例可以同时打开
+	readers := make([]*BoltStore, 2)
+	for i := range readers {
+		if readers[i], err = NewBoltStoreWithOption(path, &BoltStoreOption{ReadOnly: true, Timeout: time.Second}); err != nil {
+			t.Fatalf("reader %d: %v", i, err)
+		}
+		defer readers[i].Close()
+	}
+	for i, reader := range readers {
+		value, ok, err := reader.Get(ctx, "task:a")
+		if err != nil || !ok || string(value) != "1" {
+			t.Fatalf("reader %d Get() = %q, %v, %v", i, value, ok, err)
+		}
+		var keys []string
+		if err = reader.Scan(ctx, "task:", func(key string, value []byte) bool {
+			keys = append(keys, key)
+			return true
+		}); err != nil || len(keys) != 1 {
+			t.Fatalf("reader %d Scan() = %v, %v", i, keys, err)
+		}
+	}
+	if err = readers[0].Set(ctx, "task:b", []byte("2")); err == nil {
+		t.Fatal("Set() on read-only store should fail")
+	}
+}
+
+func TestBoltStoreReadOnlyEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.db")
+	// 其他程序创建的空数据库没有 bucket
+	db, err := bbolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	reader, err := NewBoltStoreWithOption(path, &BoltStoreOption{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if _, ok, err := reader.Get(context.Background(), "x"); ok || err != nil {
+		t.Fatalf("Get() = %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,392 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/util/gconv"
+	"sync"
+	"time"
+)
+
+const (
+	BudgetPeriodDaily   = "daily"
+	BudgetPeriodMonthly = "monthly"
+	BudgetPeriodPerJob  = "per_job"
+	BudgetPeriodBalance = "balance"
+)
+
+// Cost 花费, Money 为金额, Coins 为 RH 币
+type Cost struct {
+	Money float64 `json:"money"`
+	Coins float64 `json:"coins"`
+}
+
+func (c Cost) Add(o Cost) Cost {
+	return Cost{Money: c.Money + o.Money, Coins: c.Coins + o.Coins}
+}
+
+// exceeds 判断是否超出限制, limit 中为 0 的项不限制
+func (c Cost) exceeds(limit Cost) bool {
+	return limit.Money > 0 && c.Money > limit.Money || limit.Coins > 0 && c.Coins > limit.Coins
+}
+
+// TaskCostFromResult 从任务结果中读取花费; 每个输出项携带的是整个任务的花费, 取第一个有效项
+func TaskCostFromResult(items []*SuccessOfGetTaskResultResponseData) (cost Cost) {
+	for _, v := range items {
+		if v == nil {
+			continue
+		}
+		if v.ConsumeMoney == "" && v.ConsumeCoins == nil && v.ThirdPartyConsumeMoney == nil {
+			continue
+		}
+		cost.Money = gconv.Float64(v.ConsumeMoney)
+		if v.ThirdPartyConsumeMoney != nil {
+			cost.Money += gconv.Float64(*v.ThirdPartyConsumeMoney)
+		}
+		if v.ConsumeCoins != nil {
+			cost.Coins = gconv.Float64(*v.ConsumeCoins)
+		}
+		return cost
+	}
+	return cost
+}
+
+// BudgetLimit 花费上限, 为 0 的项不限制
+type BudgetLimit struct {
+	Daily   Cost // 自然日内已花费与执行中预估之和的上限
+	Monthly Cost // 自然月内已花费与执行中预估之和的上限
+	PerJob  Cost // 单个任务预估花费上限
+}
+
+type BudgetConfig struct {
+	Client          *RunningHubClient
+	Limit           BudgetLimit
+	DefaultEstimate Cost          // 工作流无历史记录时的预估花费
+	Store           Store         // 持久化已花费与各工作流的预估花费, 为空时仅保存在内存
+	InflightTTL     time.Duration // 执行中任务预估的保留时间, 超过后未记录结果的任务不再占用额度, 默认 24 小时
+}
+
+// BudgetExceededError 超出预算时 CreateTask 返回的错误
+type BudgetExceededError struct {
+	Period    string // daily, monthly, per_job, balance
+	Limit     Cost
+	Projected Cost // 提交该任务后的预计花费
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("budget exceeded, period: %s, limit: %+v, projected: %+v", e.Period, e.Limit, e.Projected)
+}
+
+type budgetInflightTask struct {
+	workflowId string
+	estimate   Cost
+	createdAt  time.Time
+}
+
+// budgetEstimate 工作流的历史平均花费
+type budgetEstimate struct {
+	Avg   Cost `json:"avg"`
+	Count int  `json:"count"`
+}
+
+// Budget 包装 CreateTask 的预算守卫, 根据已完成任务花费与执行中任务预估拒绝超出限制的新任务
+type Budget struct {
+	mu              sync.Mutex
+	client          *RunningHubClient
+	limit           BudgetLimit
+	defaultEstimate Cost
+	store           Store
+	inflightTTL     time.Duration
+	spent           map[string]Cost // 周期 key -> 已花费
+	inflight        map[string]*budgetInflightTask
+	estimates       map[string]*budgetEstimate
+	balance         *Cost // 最近一次对账的账户余额
+	reserveSeq      int64
+	now             func() time.Time
+}
+
+func NewBudget(in *BudgetConfig) *Budget {
+	if in.InflightTTL <= 0 {
+		in.InflightTTL = 24 * time.Hour
+	}
+	return &Budget{
+		client:          in.Client,
+		limit:           in.Limit,
+		defaultEstimate: in.DefaultEstimate,
+		store:           in.Store,
+		inflightTTL:     in.InflightTTL,
+		spent:           make(map[string]Cost),
+		inflight:        make(map[string]*budgetInflightTask),
+		estimates:       make(map[string]*budgetEstimate),
+		now:             time.Now,
+	}
+}
+
+func budgetEstimateKey(workflowId string) string {
+	return "budget:estimate:" + workflowId
+}
+
+func budgetPeriodKey(period string, t time.Time) string {
+	if period == BudgetPeriodMonthly {
+		return "budget:monthly:" + t.Format("2006-01")
+	}
+	return "budget:daily:" + t.Format("2006-01-02")
+}
+
+// Estimate 按工作流历史平均花费预估单个任务花费, 无历史记录时返回 DefaultEstimate
+func (b *Budget) Estimate(ctx context.Context, workflowId string) (Cost, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.estimateLocked(ctx, workflowId)
+}
+
+func (b *Budget) estimateLocked(ctx context.Context, workflowId string) (Cost, error) {
+	v, err := b.loadEstimateLocked(ctx, workflowId)
+	if err != nil || v.Count == 0 {
+		return b.defaultEstimate, err
+	}
+	return v.Avg, nil
+}
+
+// ObserveCost 记录工作流一次任务的实际花费, 更新该工作流的预估花费; 未经 CreateTask 提交的任务也可以用来积累历史
+func (b *Budget) ObserveCost(ctx context.Context, workflowId string, cost Cost) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.observeCostLocked(ctx, workflowId, cost)
+}
+
+func (b *Budget) observeCostLocked(ctx context.Context, workflowId string, cost Cost) error {
+	if cost == (Cost{}) {
+		return nil
+	}
+	v, err := b.loadEstimateLocked(ctx, workflowId)
+	if err != nil {
+		return err
+	}
+	// 按历史平均值预估
+	v.Count++
+	v.Avg.Money += (cost.Money - v.Avg.Money) / float64(v.Count)
+	v.Avg.Coins += (cost.Coins - v.Avg.Coins) / float64(v.Count)
+	if b.store == nil {
+		return nil
+	}
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.store.Set(ctx, budgetEstimateKey(workflowId), value)
+}
+
+// loadEstimateLocked 返回工作流的预估记录, 首次访问时从 Store 读取
+func (b *Budget) loadEstimateLocked(ctx context.Context, workflowId string) (*budgetEstimate, error) {
+	if v, ok := b.estimates[workflowId]; ok {
+		return v, nil
+	}
+	v := &budgetEstimate{}
+	if b.store != nil {
+		value, ok, err := b.store.Get(ctx, budgetEstimateKey(workflowId))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if err = json.Unmarshal(value, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	b.estimates[workflowId] = v
+	return v, nil
+}
+
+// Check 检查提交该工作流的任务是否会超出预算
+func (b *Budget) Check(ctx context.Context, workflowId string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.checkLocked(ctx, workflowId)
+}
+
+func (b *Budget) checkLocked(ctx context.Context, workflowId string) error {
+	estimate, err := b.estimateLocked(ctx, workflowId)
+	if err != nil {
+		return err
+	}
+	if estimate.exceeds(b.limit.PerJob) {
+		return &BudgetExceededError{Period: BudgetPeriodPerJob, Limit: b.limit.PerJob, Projected: estimate}
+	}
+	now := b.now()
+	var inflight Cost
+	for taskId, v := range b.inflight {
+		// 丢失结果的任务(句柄丢失、进程崩溃等)超时后不再占用额度
+		if now.Sub(v.createdAt) > b.inflightTTL {
+			delete(b.inflight, taskId)
+			continue
+		}
+		inflight = inflight.Add(v.estimate)
+	}
+	for _, period := range []string{BudgetPeriodDaily, BudgetPeriodMonthly} {
+		limit := b.limit.Daily
+		if period == BudgetPeriodMonthly {
+			limit = b.limit.Monthly
+		}
+		spent, err := b.spentLocked(ctx, budgetPeriodKey(period, now))
+		if err != nil {
+			return err
+		}
+		if projected := spent.Add(inflight).Add(estimate); projected.exceeds(limit) {
+			return &BudgetExceededError{Period: period, Limit: limit, Projected: projected}
+		}
+	}
+	if b.balance != nil {
+		projected := inflight.Add(estimate)
+		if projected.Money > 0 && projected.Money > b.balance.Money || projected.Coins > 0 && projected.Coins > b.balance.Coins {
+			return &BudgetExceededError{Period: BudgetPeriodBalance, Limit: *b.balance, Projected: projected}
+		}
+	}
+	return nil
+}
+
+// CreateTask 检查预算后创建任务, 超出预算时返回 *BudgetExceededError
+func (b *Budget) CreateTask(ctx context.Context, payloadData *CreateTaskReq) (res *CreateTaskRes, err error) {
+	if payloadData == nil {
+		return nil, gerror.New("create task request cannot be nil")
+	}
+	b.mu.Lock()
+	if err = b.checkLocked(ctx, payloadData.WorkflowId); err != nil {
+		b.mu.Unlock()
+		return nil, err
+	}
+	estimate, err := b.estimateLocked(ctx, payloadData.WorkflowId)
+	if err != nil {
+		b.mu.Unlock()
+		return nil, err
+	}
+	// 先占用预估额度, 避免并发提交同时通过检查
+	b.reserveSeq++
+	reserveId := fmt.Sprintf("reserve:%d", b.reserveSeq)
+	b.inflight[reserveId] = &budgetInflightTask{
+		workflowId: payloadData.WorkflowId,
+		estimate:   estimate,
+		createdAt:  b.now(),
+	}
+	b.mu.Unlock()
+
+	res, err = b.client.CreateTask(ctx, payloadData)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	task := b.inflight[reserveId]
+	delete(b.inflight, reserveId)
+	if err != nil {
+		return nil, err
+	}
+	b.inflight[res.TaskId] = task
+	return res, nil
+}
+
+// RecordResult 记录任务结束后的实际花费, 并更新该工作流的预估花费;
+// 只记录经 CreateTask 提交且未记录过的任务, 重复调用不会重复计入
+func (b *Budget) RecordResult(ctx context.Context, taskId string, res *GetTaskStatusAndResultRes) error {
+	if res == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	task, ok := b.inflight[taskId]
+	if !ok {
+		return nil
+	}
+	delete(b.inflight, taskId)
+	cost := TaskCostFromResult(res.SuccessItems)
+	if cost == (Cost{}) {
+		return nil
+	}
+	if err := b.observeCostLocked(ctx, task.workflowId, cost); err != nil {
+		return err
+	}
+	if b.balance != nil {
+		b.balance.Money -= cost.Money
+		b.balance.Coins -= cost.Coins
+	}
+	now := b.now()
+	for _, period := range []string{BudgetPeriodDaily, BudgetPeriodMonthly} {
+		key := budgetPeriodKey(period, now)
+		spent, err := b.spentLocked(ctx, key)
+		if err != nil {
+			return err
+		}
+		if err = b.setSpentLocked(ctx, key, spent.Add(cost)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Forget 移除执行中的任务, 用于任务取消等不再产生花费的情况
+func (b *Budget) Forget(taskId string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.inflight, taskId)
+}
+
+// Spent 返回当日与当月的已花费
+func (b *Budget) Spent(ctx context.Context) (daily Cost, monthly Cost, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if daily, err = b.spentLocked(ctx, budgetPeriodKey(BudgetPeriodDaily, now)); err != nil {
+		return daily, monthly, err
+	}
+	monthly, err = b.spentLocked(ctx, budgetPeriodKey(BudgetPeriodMonthly, now))
+	return daily, monthly, err
+}
+
+// Reconcile 通过 GetAccountInfo 获取账户余额, 之后的检查不允许执行中任务的预估超过余额
+func (b *Budget) Reconcile(ctx context.Context) (res *GetAccountRes, err error) {
+	res, err = b.client.GetAccountInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := ParseBalanceSnapshot(res)
+	if err != nil {
+		return res, err
+	}
+	b.SetBalance(snapshot.cost())
+	return res, nil
+}
+
+// SetBalance 设置账户余额
+func (b *Budget) SetBalance(balance Cost) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.balance = &balance
+}
+
+func (b *Budget) spentLocked(ctx context.Context, key string) (Cost, error) {
+	if v, ok := b.spent[key]; ok || b.store == nil {
+		return v, nil
+	}
+	value, ok, err := b.store.Get(ctx, key)
+	if err != nil || !ok {
+		return Cost{}, err
+	}
+	var cost Cost
+	if err = json.Unmarshal(value, &cost); err != nil {
+		return Cost{}, err
+	}
+	b.spent[key] = cost
+	return cost, nil
+}
+
+func (b *Budget) setSpentLocked(ctx context.Context, key string, cost Cost) error {
+	b.spent[key] = cost
+	if b.store == nil {
+		return nil
+	}
+	value, err := json.Marshal(cost)
+	if err != nil {
+		return err
+	}
+	return b.store.Set(ctx, key, value)
+}
@@ -0,0 +1,159 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBudgetEstimatePersists(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	cfg := &BudgetConfig{DefaultEstimate: Cost{Money: 1}, Store: store}
+	budget := NewBudget(cfg)
+	if got, err := budget.Estimate(ctx, "wf"); err != nil || got != (Cost{Money: 1}) {
+		t.Fatalf("Estimate() without history = %+v, %v", got, err)
+	}
+	for _, v := range []Cost{{Money: 2, Coins: 10}, {Money: 4, Coins: 30}, {}} {
+		if err := budget.ObserveCost(ctx, "wf", v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := Cost{Money: 3, Coins: 20}
+	if got, _ := budget.Estimate(ctx, "wf"); got != want {
+		t.Fatalf("Estimate() = %+v, want %+v", got, want)
+	}
+	// 重启后从 Store 恢复预估并继续累计
+	restarted := NewBudget(cfg)
+	if got, _ := restarted.Estimate(ctx, "wf"); got != want {
+		t.Fatalf("Estimate() after restart = %+v, want %+v", got, want)
+	}
+	if err := restarted.ObserveCost(ctx, "wf", Cost{Money: 6, Coins: 50}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := NewBudget(cfg).Estimate(ctx, "wf"); got != (Cost{Money: 4, Coins: 30}) {
+		t.Fatalf("Estimate() after second restart = %+v", got)
+	}
+	if got, _ := restarted.Estimate(ctx, "other"); got != cfg.DefaultEstimate {
+		t.Fatalf("Estimate() of other workflow = %+v", got)
+	}
+}
+
+func TestBudgetCreateTask(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeRunningHub()
+	fake.Money = "2"
+	client := fake.client(t)
+	store := NewMemoryStore()
+	cfg := &BudgetConfig{
+		Client:          client,
+		Limit:           BudgetLimit{Daily: Cost{Money: 5}},
+		DefaultEstimate: Cost{Money: 1},
+		Store:           store,
+	}
+	budget := NewBudget(cfg)
+	// 按默认预估可以同时提交 5 个任务
+	var taskIds []string
+	for i := 0; i < 5; i++ {
+		res, err := budget.CreateTask(ctx, testCreateTaskReq())
+		if err != nil {
+			t.Fatalf("task %d: %v", i, err)
+		}
+		taskIds = append(taskIds, res.TaskId)
+	}
+	var exceeded *BudgetExceededError
+	if _, err := budget.CreateTask(ctx, testCreateTaskReq()); !errors.As(err, &exceeded) || exceeded.Period != BudgetPeriodDaily {
+		t.Fatalf("CreateTask() err = %v, want daily budget exceeded", err)
+	}
+	// 结束一个任务后, 已花费 2 + 执行中 4 + 新任务按实际花费预估 2 仍超出限制
+	res, err := client.GetTaskStatusAndResult(ctx, taskIds[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = budget.RecordResult(ctx, taskIds[0], res); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = budget.CreateTask(ctx, testCreateTaskReq()); !errors.As(err, &exceeded) {
+		t.Fatalf("CreateTask() err = %v, want budget exceeded", err)
+	}
+	for _, taskId := range taskIds[1:] {
+		budget.Forget(taskId)
+	}
+	daily, _, err := budget.Spent(ctx)
+	if err != nil || daily != (Cost{Money: 2}) {
+		t.Fatalf("Spent() = %+v, %v", daily, err)
+	}
+	// 重启后已花费与预估均从 Store 恢复: 2 + 2 不超出, 再提交一个则超出
+	restarted := NewBudget(cfg)
+	if err = restarted.Check(ctx, "wf"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = restarted.CreateTask(ctx, testCreateTaskReq()); err != nil {
+		t.Fatal(err)
+	}
+	if err = restarted.Check(ctx, "wf"); !errors.As(err, &exceeded) {
+		t.Fatalf("Check() err = %v, want budget exceeded", err)
+	}
+}
+
+func TestBudgetRecordResultOnce(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeRunningHub()
+	fake.Money = "2"
+	client := fake.client(t)
+	budget := NewBudget(&BudgetConfig{Client: client, DefaultEstimate: Cost{Money: 1}})
+	budget.SetBalance(Cost{Money: 10})
+	task, err := budget.CreateTask(ctx, testCreateTaskReq())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.GetTaskStatusAndResult(ctx, task.TaskId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, taskId := range []string{task.TaskId, task.TaskId, "unknown-task"} {
+		if err = budget.RecordResult(ctx, taskId, res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	daily, monthly, err := budget.Spent(ctx)
+	if err != nil || daily != (Cost{Money: 2}) || monthly != (Cost{Money: 2}) {
+		t.Fatalf("Spent() = %+v, %+v, %v", daily, monthly, err)
+	}
+	if budget.balance.Money != 8 {
+		t.Fatalf("balance = %+v, want 8", *budget.balance)
+	}
+	if got, _ := budget.Estimate(ctx, "wf"); got != (Cost{Money: 2}) {
+		t.Fatalf("Estimate() = %+v", got)
+	}
+}
+
+func TestBudgetInflightTTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	budget := NewBudget(&BudgetConfig{
+		Client:          newFakeRunningHub().client(t),
+		Limit:           BudgetLimit{Daily: Cost{Money: 2}},
+		DefaultEstimate: Cost{Money: 1},
+		InflightTTL:     time.Hour,
+	})
+	budget.now = func() time.Time { return now }
+	for i := 0; i < 2; i++ {
+		if _, err := budget.CreateTask(ctx, testCreateTaskReq()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var exceeded *BudgetExceededError
+	if err := budget.Check(ctx, "wf"); !errors.As(err, &exceeded) {
+		t.Fatalf("Check() err = %v, want budget exceeded", err)
+	}
+	// 没有结果的任务超过 InflightTTL 后释放额度
+	now = now.Add(time.Hour + time.Second)
+	if err := budget.Check(ctx, "wf"); err != nil {
+		t.Fatalf("Check() after ttl = %v", err)
+	}
+	if len(budget.inflight) != 0 {
+		t.Fatalf("inflight = %d, want 0", len(budget.inflight))
+	}
+}
@@ -0,0 +1,51 @@
+package runninghub_client_utils
+
+import (
+	"encoding/json"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// SetWorkflow 设置提交的完整工作流, 支持 JSON 字符串、[]byte、*GetWorkflowJSONRes、
+// map[string]WorkflowJSONNodeInfo 或其他可序列化为 JSON 对象的值, 接口要求以字符串形式提交
+func (r *CreateTaskReq) SetWorkflow(workflow interface{}) error {
+	var data []byte
+	switch v := workflow.(type) {
+	case nil:
+		r.Workflow = ""
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	case *GetWorkflowJSONRes:
+		if v == nil {
+			return gerror.New("workflow cannot be nil")
+		}
+		return r.SetWorkflow(v.WorkflowData)
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return gerror.Wrap(err, "encode workflow fail")
+		}
+	}
+	var nodes map[string]json.RawMessage
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return gerror.Wrap(err, "workflow must be a JSON object of api format")
+	}
+	if len(nodes) == 0 {
+		return gerror.New("workflow cannot be empty")
+	}
+	r.Workflow = string(data)
+	return nil
+}
+
+// WorkflowData 解析 Workflow 字段, 未设置时返回 nil
+func (r *CreateTaskReq) WorkflowData() (res map[string]WorkflowJSONNodeInfo, err error) {
+	if r.Workflow == "" {
+		return nil, nil
+	}
+	if err = json.Unmarshal([]byte(r.Workflow), &res); err != nil {
+		return nil, gerror.Wrap(err, "decode workflow fail")
+	}
+	return res, nil
+}
@@ -0,0 +1,146 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testWorkflow = `{"6":{"class_type":"CLIPTextEncode","inputs":{"text":"a cat"},"_meta":{"title":"prompt"}}}`
+
+func TestSetWorkflow(t *testing.T) {
+	tests := []struct {
+		name     string
+		workflow interface{}
+		want     string // 为空表示清空
+		wantErr  bool
+	}{
+		{name: "string", workflow: testWorkflow, want: testWorkflow},
+		{name: "bytes", workflow: []byte(testWorkflow), want: testWorkflow},
+		{name: "map", workflow: map[string]WorkflowJSONNodeInfo{"6": {ClassType: "CLIPTextEncode"}}, want: `{"6":{"class_type":"CLIPTextEncode","inputs":null,"_meta":null}}`},
+		{name: "GetWorkflowJSON result", workflow: &GetWorkflowJSONRes{WorkflowData: map[string]WorkflowJSONNodeInfo{"6": {ClassType: "CLIPTextEncode"}}}, want: `{"6":{"class_type":"CLIPTextEncode","inputs":null,"_meta":null}}`},
+		{name: "nil clears", workflow: nil},
+		{name: "nil GetWorkflowJSON result", workflow: (*GetWorkflowJSONRes)(nil), wantErr: true},
+		{name: "array", workflow: `[{"class_type":"CLIPTextEncode"}]`, wantErr: true},
+		{name: "string value", workflow: `"workflow"`, wantErr: true},
+		{name: "number", workflow: `1`, wantErr: true},
+		{name: "null", workflow: `null`, wantErr: true},
+		{name: "empty object", workflow: `{}`, wantErr: true},
+		{name: "empty string", workflow: "", wantErr: true},
+		{name: "invalid json", workflow: `{"6":`, wantErr: true},
+		{name: "slice", workflow: []string{"a"}, wantErr: true},
+		{name: "unencodable", workflow: func() {}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &CreateTaskReq{Workflow: `{"old":{}}`}
+			err := req.SetWorkflow(tt.workflow)
+			if tt.wantErr {
+				// 校验失败时保留原值
+				if err == nil || req.Workflow != `{"old":{}}` {
+					t.Fatalf("SetWorkflow() err = %v, workflow = %q", err, req.Workflow)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.Workflow != tt.want {
+				t.Fatalf("Workflow = %s, want %s", req.Workflow, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkflowData(t *testing.T) {
+	req := &CreateTaskReq{}
+	if data, err := req.WorkflowData(); err != nil || data != nil {
+		t.Fatalf("empty WorkflowData() = %v, %v", data, err)
+	}
+	if err := req.SetWorkflow(testWorkflow); err != nil {
+		t.Fatal(err)
+	}
+	data, err := req.WorkflowData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node := data["6"]; node.ClassType != "CLIPTextEncode" || node.Inputs["text"] != "a cat" || node.Meta["title"] != "prompt" {
+		t.Fatalf("WorkflowData() = %+v", data)
+	}
+	req.Workflow = `[1]`
+	if _, err = req.WorkflowData(); err == nil {
+		t.Fatal("WorkflowData() of non-object should fail")
+	}
+}
+
+func TestCreateTaskRequestBody(t *testing.T) {
+	var bodies []map[string]json.RawMessage
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		bodies = append(bodies, body)
+		writeTestResponse(w, 0, "success", &CreateTaskRes{TaskId: "task-1"})
+	}))
+	ctx := context.Background()
+
+	// 未设置的 instanceType 等可选字段不出现在请求体中
+	if _, err := client.CreateTask(ctx, testCreateTaskReq()); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"instanceType", "usePersonalQueue", "addMetadata", "workflow"} {
+		if _, ok := bodies[0][k]; ok {
+			t.Fatalf("body has %s: %s", k, bodies[0][k])
+		}
+	}
+
+	// 只提交工作流时 nodeInfoList 为 [] 而不是 null
+	req := &CreateTaskReq{WorkflowId: "wf", InstanceType: "plus", UsePersonalQueue: true, AddMetadata: true}
+	if err := req.SetWorkflow(testWorkflow); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CreateTask(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	body := bodies[1]
+	if string(body["nodeInfoList"]) != "[]" || string(body["instanceType"]) != `"plus"` || string(body["usePersonalQueue"]) != "true" || string(body["addMetadata"]) != "true" {
+		t.Fatalf("body = %v", body)
+	}
+	var workflow string
+	if err := json.Unmarshal(body["workflow"], &workflow); err != nil || workflow != testWorkflow {
+		t.Fatalf("workflow = %s, %v", body["workflow"], err)
+	}
+
+	// 没有 nodeInfoList 与 workflow 时不发送请求
+	if _, err := client.CreateTask(ctx, &CreateTaskReq{WorkflowId: "wf"}); err == nil || len(bodies) != 2 {
+		t.Fatalf("CreateTask() err = %v, requests = %d", err, len(bodies))
+	}
+}
+
+func TestRunValidatesWorkflow(t *testing.T) {
+	for _, workflow := range []string{`[1]`, `{}`, `not json`} {
+		fake := newFakeRunningHub()
+		_, err := fake.client(t).Run(context.Background(), testRunRequest(&RunOptions{PollInterval: 10 * time.Millisecond, Workflow: workflow}))
+		if err == nil || !strings.Contains(err.Error(), "workflow") {
+			t.Fatalf("Run(%s) err = %v", workflow, err)
+		}
+		if n := fake.createCount(); n != 0 {
+			t.Fatalf("Run(%s) created %d tasks", workflow, n)
+		}
+	}
+
+	fake := newFakeRunningHub()
+	res, err := fake.client(t).Run(context.Background(), testRunRequest(&RunOptions{PollInterval: 10 * time.Millisecond, Workflow: testWorkflow}))
+	if err != nil || res.TaskId == "" {
+		t.Fatalf("Run() = %+v, %v", res, err)
+	}
+	if task := fake.task(res.TaskId); task.req.Workflow != testWorkflow {
+		t.Fatalf("submitted workflow = %q", task.req.Workflow)
+	}
+}
@@ -0,0 +1,185 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Friday-fighting/runninghub_tools/utility"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultTaskOutputNameTemplate = "{taskId}_{nodeId}_{index}.{ext}"
+
+// DownloadTaskOutputs 并行下载任务输出文件, 按模板生成确定的文件名, 并为每个文件写入 JSON 元数据
+func DownloadTaskOutputs(ctx context.Context, res *GetTaskStatusAndResultRes, dir string, opts *DownloadTaskOutputsOption) (out *DownloadTaskOutputsRes, err error) {
+	if res == nil {
+		return nil, gerror.New("task result cannot be nil")
+	}
+	if opts == nil {
+		opts = &DownloadTaskOutputsOption{}
+	}
+	if opts.NameTemplate == "" {
+		opts.NameTemplate = DefaultTaskOutputNameTemplate
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if dir == "" {
+		dir = filepath.Join("temp", "cacheTaskOutputs")
+	}
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	out = &DownloadTaskOutputsRes{
+		Items: make([]*DownloadTaskOutputItem, len(res.SuccessItems)),
+	}
+	// 先生成全部文件名, 模板不含 {index} 等字段时不同输出可能同名, 并行下载会写同一个文件
+	localPaths := make(map[string]int, len(res.SuccessItems))
+	for i, v := range res.SuccessItems {
+		if v == nil {
+			out.Items[i] = &DownloadTaskOutputItem{Index: i, Err: gerror.New("empty output item")}
+			continue
+		}
+		ext := taskOutputExt(v)
+		fileName := renderTaskOutputName(opts.NameTemplate, opts.TaskId, v.NodeId, i, ext)
+		item := &DownloadTaskOutputItem{
+			Index:     i,
+			NodeId:    v.NodeId,
+			FileUrl:   v.FileUrl,
+			FileType:  v.FileType,
+			LocalPath: filepath.Join(dir, fileName),
+		}
+		item.SidecarPath = item.LocalPath + ".json"
+		if prev, ok := localPaths[item.LocalPath]; ok {
+			return nil, gerror.Newf("output(index: %d) and output(index: %d) are both named %s, add {index} to the name template", prev, i, fileName)
+		}
+		localPaths[item.LocalPath] = i
+		out.Items[i] = item
+	}
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, opts.Concurrency)
+	)
+	for i, v := range res.SuccessItems {
+		if v == nil {
+			continue
+		}
+		item := out.Items[i]
+		wg.Add(1)
+		go func(data *SuccessOfGetTaskResultResponseData) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				item.Err = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+			item.Err = downloadTaskOutput(ctx, opts, data, item)
+		}(v)
+	}
+	wg.Wait()
+	for _, v := range out.Items {
+		if v.Err != nil {
+			return out, gerror.Wrapf(v.Err, "download output(index: %d, nodeId: %s) fail", v.Index, v.NodeId)
+		}
+	}
+	return out, nil
+}
+
+func downloadTaskOutput(ctx context.Context, opts *DownloadTaskOutputsOption, data *SuccessOfGetTaskResultResponseData, item *DownloadTaskOutputItem) error {
+	if data.FileUrl == "" {
+		return gerror.New("fileUrl is empty")
+	}
+	if size, ok := checkTaskOutputDownloaded(item); ok {
+		item.Size = size
+		item.Skipped = true
+		return nil
+	}
+	start := time.Now()
+	size, err := utility.DownloadToFile(ctx, data.FileUrl, item.LocalPath, opts.Timeout)
+	if err != nil {
+		return err
+	}
+	item.Size = size
+	sidecar := &TaskOutputSidecar{
+		TaskId:                 opts.TaskId,
+		NodeId:                 data.NodeId,
+		Index:                  item.Index,
+		FileUrl:                data.FileUrl,
+		FileType:               data.FileType,
+		FileName:               filepath.Base(item.LocalPath),
+		Size:                   size,
+		TaskCostTime:           data.TaskCostTime,
+		ConsumeMoney:           data.ConsumeMoney,
+		ConsumeCoins:           data.ConsumeCoins,
+		ThirdPartyConsumeMoney: data.ThirdPartyConsumeMoney,
+		DownloadedAt:           time.Now().Format(time.RFC3339),
+		DownloadCostMs:         time.Since(start).Milliseconds(),
+	}
+	body, err := json.MarshalIndent(sidecar, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(item.SidecarPath, body, os.ModePerm)
+}
+
+// checkTaskOutputDownloaded 文件与元数据均存在且大小一致时视为已下载
+func checkTaskOutputDownloaded(item *DownloadTaskOutputItem) (size int64, ok bool) {
+	stat, err := os.Stat(item.LocalPath)
+	if err != nil || stat.IsDir() {
+		return 0, false
+	}
+	body, err := os.ReadFile(item.SidecarPath)
+	if err != nil {
+		return 0, false
+	}
+	var sidecar TaskOutputSidecar
+	if err = json.Unmarshal(body, &sidecar); err != nil {
+		return 0, false
+	}
+	if sidecar.FileUrl != item.FileUrl || sidecar.Size != stat.Size() {
+		return 0, false
+	}
+	return stat.Size(), true
+}
+
+func taskOutputExt(data *SuccessOfGetTaskResultResponseData) string {
+	ext := strings.TrimPrefix(strings.ToLower(data.FileType), ".")
+	if ext == "" {
+		if u, err := url.Parse(data.FileUrl); err == nil {
+			ext = strings.TrimPrefix(strings.ToLower(path.Ext(u.Path)), ".")
+		}
+	}
+	if ext == "" {
+		ext = "bin"
+	}
+	return ext
+}
+
+func renderTaskOutputName(template string, taskId string, nodeId string, index int, ext string) string {
+	replacer := strings.NewReplacer(
+		"{taskId}", sanitizeFileNamePart(taskId),
+		"{nodeId}", sanitizeFileNamePart(nodeId),
+		"{index}", strconv.Itoa(index),
+		"{ext}", sanitizeFileNamePart(ext),
+	)
+	return replacer.Replace(template)
+}
+
+func sanitizeFileNamePart(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,88 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRenderTaskOutputName(t *testing.T) {
+	tests := []struct {
+		template string
+		nodeId   string
+		want     string
+	}{
+		{DefaultTaskOutputNameTemplate, "9", "t1_9_2.png"},
+		{"{nodeId}-{index}.{ext}", "9", "9-2.png"},
+		{"out.{ext}", "9", "out.png"},
+		{DefaultTaskOutputNameTemplate, "a/b:c", "t1_a_b_c_2.png"},
+	}
+	for _, tt := range tests {
+		if got := renderTaskOutputName(tt.template, "t1", tt.nodeId, 2, "png"); got != tt.want {
+			t.Errorf("renderTaskOutputName(%q, %q) = %q, want %q", tt.template, tt.nodeId, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadTaskOutputs(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("content of " + r.URL.Path))
+	}))
+	defer server.Close()
+	res := &GetTaskStatusAndResultRes{
+		SuccessItems: []*SuccessOfGetTaskResultResponseData{
+			{FileUrl: server.URL + "/a.png", FileType: "png", NodeId: "9"},
+			{FileUrl: server.URL + "/b.png", FileType: "png", NodeId: "9"},
+		},
+	}
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	_, err := DownloadTaskOutputs(ctx, res, dir, &DownloadTaskOutputsOption{TaskId: "t1", NameTemplate: "{taskId}_{nodeId}.{ext}"})
+	if err == nil {
+		t.Fatal("expected duplicate name error")
+	}
+	if n := requests.Load(); n != 0 {
+		t.Fatalf("downloads started before name check: %d requests", n)
+	}
+
+	out, err := DownloadTaskOutputs(ctx, res, dir, &DownloadTaskOutputsOption{TaskId: "t1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range out.Items {
+		body, err := os.ReadFile(v.LocalPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "content of /" + []string{"a", "b"}[i] + ".png"; string(body) != want {
+			t.Errorf("item %d content = %q, want %q", i, body, want)
+		}
+		if filepath.Base(v.LocalPath) != []string{"t1_9_0.png", "t1_9_1.png"}[i] {
+			t.Errorf("item %d path = %s", i, v.LocalPath)
+		}
+		if _, err = os.Stat(v.SidecarPath); err != nil {
+			t.Errorf("item %d sidecar: %v", i, err)
+		}
+	}
+
+	requests.Store(0)
+	out, err = DownloadTaskOutputs(ctx, res, dir, &DownloadTaskOutputsOption{TaskId: "t1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range out.Items {
+		if !v.Skipped {
+			t.Errorf("item %d not skipped", v.Index)
+		}
+	}
+	if n := requests.Load(); n != 0 {
+		t.Fatalf("already downloaded outputs requested again: %d", n)
+	}
+}
@@ -0,0 +1,161 @@
+package runninghub_client_utils
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	LangZhCN = "zh-CN"
+	LangEnUS = "en-US"
+)
+
+const defaultLang = LangZhCN
+
+// defaultLangRegions 只给出语言时优先使用的地区
+var defaultLangRegions = map[string]string{
+	"zh": LangZhCN,
+	"en": LangEnUS,
+}
+
+var (
+	errorMessageCatalogMu sync.RWMutex
+	// errorMessageCatalog 语言 -> SignMsg -> 提示, zh-CN 默认取 errorInfoMap 中的 Msg;
+	// 失败分类的建议提示以 FAILURE_ 加大写分类名为键
+	errorMessageCatalog = map[string]map[string]string{
+		LangEnUS: {
+			"PARAMS_INVALID":                          "The request contains invalid or missing parameters",
+			"WORKFLOW_NOT_EXISTS":                     "The specified workflow does not exist",
+			"TOKEN_INVALID":                           "The API path is misspelled",
+			"TASK_INSTANCE_MAXED":                     "Not enough instances available for the exclusive API",
+			"TASK_CREATE_FAILED_BY_NOT_ENOUGH_WALLET": "Insufficient wallet balance",
+			"TASK_QUEUE_MAXED":                        "The shared API has reached your concurrency limit",
+			"TASK_NOT_FOUNED":                         "Task not found",
+			"VALIDATE_PROMPT_FAILED":                  "Workflow validation failed (prompt and node configuration)",
+			"TASK_USER_EXCLAPI_INSTANCE_NOT_FOUND":    "Exclusive API instance not found",
+			"TASK_USER_EXCLAPI_REQUIRED":              "Exclusive membership has expired",
+			"APIKEY_UNSUPPORTED_FREE_USER":            "API keys are not available to free users",
+			"APIKEY_UNAUTHORIZED":                     "The API key is unauthorized or has expired",
+			"APIKEY_INVALID_NODE_INFO":                "nodeInfoList does not match the bound workflow",
+			"APIKEY_TASK_IS_RUNNING":                  "The task is running",
+			"APIKEY_TASK_STATUS_ERROR":                "The task failed",
+			"APIKEY_USER_NOT_FOUND":                   "User not found",
+			"APIKEY_TASK_NOT_FOUND":                   "Task not found",
+			"APIKEY_UPLOAD_FAILED":                    "File upload failed",
+			"APIKEY_FILE_SIZE_EXCEEDED":               "File size exceeds the limit",
+			"WORKFLOW_NOT_SAVED_OR_NOT_RUNNING":       "Save and run the workflow on the platform before calling the API",
+			"CORPAPIKEY_INVALID":                      "The enterprise API key is invalid",
+			"CORPAPIKEY_INSUFFICIENT_FUNDS":           "Insufficient enterprise balance",
+			"APIKEY_TASK_IS_QUEUED":                   "The task is queued",
+			"WEBAPP_NOT_EXISTS":                       "The WebApp does not exist",
+			"UNKNOWN_ERROR":                           "Unknown error (uncaught exception)",
+
+			"FAILURE_VRAM_ALERT":          "GPU memory alert, please retry later or lower the resolution",
+			"FAILURE_CONTENT_MODERATION":  "The content did not pass moderation, please change the input and retry",
+			"FAILURE_CUDA_OOM":            "Out of GPU memory, please lower the resolution or batch size and retry",
+			"FAILURE_MISSING_CUSTOM_NODE": "The workflow uses a custom node that is not supported",
+			"FAILURE_MISSING_MODEL":       "A model referenced by the workflow does not exist, please check the model name",
+			"FAILURE_INPUT_DECODE_ERROR":  "The input image cannot be decoded, please check the image format",
+			"FAILURE_TIMEOUT":             "The task timed out, please retry later",
+			"FAILURE_THIRD_PARTY_API":     "A third-party API call failed, please retry later",
+		},
+	}
+)
+
+// RegisterErrorMessages 注册或覆盖某个语言的提示, key 为 SignMsg 或 FAILURE_ 加大写分类名
+func RegisterErrorMessages(lang string, messages map[string]string) {
+	lang = normalizeLang(lang)
+	errorMessageCatalogMu.Lock()
+	defer errorMessageCatalogMu.Unlock()
+	catalog, ok := errorMessageCatalog[lang]
+	if !ok {
+		catalog = make(map[string]string, len(messages))
+		errorMessageCatalog[lang] = catalog
+	}
+	for k, v := range messages {
+		catalog[k] = v
+	}
+}
+
+// LocalizeErrorMessage 按语言查找提示, 依次尝试完整语言、语言前缀、该语言的默认地区、
+// 该语言的其他地区(按名称排序)与 zh-CN, 均未找到时返回 fallback
+func LocalizeErrorMessage(lang string, key string, fallback string) string {
+	lang = normalizeLang(lang)
+	base := lang
+	if idx := strings.Index(lang, "-"); idx > 0 {
+		base = lang[:idx]
+	}
+	errorMessageCatalogMu.RLock()
+	defer errorMessageCatalogMu.RUnlock()
+	candidates := []string{lang, base}
+	if v, ok := defaultLangRegions[base]; ok {
+		candidates = append(candidates, v)
+	}
+	regions := make([]string, 0)
+	for catalogLang := range errorMessageCatalog {
+		if strings.HasPrefix(catalogLang, base+"-") {
+			regions = append(regions, catalogLang)
+		}
+	}
+	sort.Strings(regions)
+	candidates = append(candidates, regions...)
+	candidates = append(candidates, defaultLang)
+	for _, v := range candidates {
+		if msg, ok := errorMessageCatalog[v][key]; ok {
+			return msg
+		}
+		if v == defaultLang {
+			break
+		}
+	}
+	return fallback
+}
+
+// normalizeLang 统一为 zh-CN / en-US 形式, 空值视为 zh-CN
+func normalizeLang(lang string) string {
+	lang = strings.ReplaceAll(strings.TrimSpace(lang), "_", "-")
+	if lang == "" {
+		return defaultLang
+	}
+	parts := strings.SplitN(lang, "-", 2)
+	if len(parts) == 1 {
+		return strings.ToLower(parts[0])
+	}
+	return strings.ToLower(parts[0]) + "-" + strings.ToUpper(parts[1])
+}
+
+func failureMessageKey(category string) string {
+	return "FAILURE_" + strings.ToUpper(category)
+}
+
+// Localized 返回指定语言的错误提示
+func (e *ErrorInfo) Localized(lang string) string {
+	return LocalizeErrorMessage(lang, e.SignMsg, e.Msg)
+}
+
+// LocalizedSuggestion 返回指定语言的失败处理建议
+func (e *ErrorInfo) LocalizedSuggestion(lang string) string {
+	if e.Category == "" || e.Category == FailureCategoryUnknown {
+		return e.Suggestion
+	}
+	return LocalizeErrorMessage(lang, failureMessageKey(e.Category), e.Suggestion)
+}
+
+// Localized 返回指定语言的错误提示, 未知 code 时返回接口原始信息
+func (e *ApiError) Localized(lang string) string {
+	if info, ok := errorInfoMap[e.Code]; ok {
+		return LocalizeErrorMessage(lang, info.SignMsg, info.Msg)
+	}
+	return e.Msg
+}
+
+// GetErrorInfo 与包级 GetErrorInfo 相同, Msg 与 Suggestion 使用客户端配置的语言
+func (c *RunningHubClient) GetErrorInfo(code int, msg string, failReason *FailedReason) (res *ErrorInfo) {
+	res = GetErrorInfo(code, msg, failReason)
+	if _, ok := errorInfoMap[code]; ok {
+		res.Msg = res.Localized(c.Lang)
+	}
+	res.Suggestion = res.LocalizedSuggestion(c.Lang)
+	return res
+}
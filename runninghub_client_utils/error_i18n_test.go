@@ -0,0 +1,64 @@
+package runninghub_client_utils
+
+import "testing"
+
+func TestLocalizeErrorMessage(t *testing.T) {
+	RegisterErrorMessages("en_GB", map[string]string{
+		"TEST_I18N_REGIONAL": "gb",
+		"TEST_I18N_GB_ONLY":  "gb only",
+	})
+	RegisterErrorMessages("en-AU", map[string]string{
+		"TEST_I18N_REGIONAL": "au",
+		"TEST_I18N_AU_ONLY":  "au only",
+	})
+	RegisterErrorMessages("en-US", map[string]string{
+		"TEST_I18N_REGIONAL": "us",
+	})
+	tests := []struct {
+		lang string
+		key  string
+		want string
+	}{
+		{"", "PARAMS_INVALID", "fallback"},
+		{"zh-CN", "PARAMS_INVALID", "fallback"},
+		{"en-US", "PARAMS_INVALID", "The request contains invalid or missing parameters"},
+		{"en_us", "PARAMS_INVALID", "The request contains invalid or missing parameters"},
+		{"en", "PARAMS_INVALID", "The request contains invalid or missing parameters"},
+		{"en-GB", "PARAMS_INVALID", "The request contains invalid or missing parameters"},
+		{"fr-FR", "PARAMS_INVALID", "fallback"},
+		// 只给出语言时优先默认地区, 与注册顺序和 map 遍历顺序无关
+		{"en", "TEST_I18N_REGIONAL", "us"},
+		{"en-GB", "TEST_I18N_REGIONAL", "gb"},
+		{"en-NZ", "TEST_I18N_REGIONAL", "us"},
+		// 默认地区没有时按地区名称排序
+		{"en", "TEST_I18N_AU_ONLY", "au only"},
+		{"en", "TEST_I18N_GB_ONLY", "gb only"},
+		{"en-GB", "TEST_I18N_AU_ONLY", "au only"},
+		{"zh", "TEST_I18N_REGIONAL", "fallback"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang+"/"+tt.key, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				if got := LocalizeErrorMessage(tt.lang, tt.key, "fallback"); got != tt.want {
+					t.Fatalf("LocalizeErrorMessage(%q, %q) = %q, want %q", tt.lang, tt.key, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeLang(t *testing.T) {
+	tests := map[string]string{
+		"":       LangZhCN,
+		" en ":   "en",
+		"EN_us":  LangEnUS,
+		"zh-cn":  LangZhCN,
+		"pt-br":  "pt-BR",
+		"zh_TW ": "zh-TW",
+	}
+	for in, want := range tests {
+		if got := normalizeLang(in); got != want {
+			t.Errorf("normalizeLang(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
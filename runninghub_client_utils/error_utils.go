@@ -1,6 +1,9 @@
 package runninghub_client_utils
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+)
 
 type ErrorInfo struct {
 	Code         int           `json:"code"`
@@ -8,6 +11,29 @@ type ErrorInfo struct {
 	Msg          string        `json:"msg"`
 	SubCode      int           `json:"sub_code"`
 	FailedReason *FailedReason `json:"failed_reason"`
+	Category     string        `json:"category"`   // 失败分类, 见 FailureCategory*
+	Retryable    bool          `json:"retryable"`  // 是否建议重试
+	Suggestion   string        `json:"suggestion"` // 建议展示给用户的提示
+}
+
+// ApiError 接口返回非 0 code 时的错误
+type ApiError struct {
+	Op   string `json:"op"`
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+func (e *ApiError) Error() string {
+	return fmt.Sprintf("%s fail, code: %d, msg: %s", e.Op, e.Code, e.Msg)
+}
+
+// ApiErrorCode 取出 err 链中 ApiError 的 code
+func ApiErrorCode(err error) (code int, ok bool) {
+	var apiErr *ApiError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code, true
+	}
+	return 0, false
 }
 
 type errorInfo struct {
@@ -156,20 +182,28 @@ func GetErrorInfo(code int, msg string, failReason *FailedReason) (res *ErrorInf
 		res.Msg = value.Msg
 		if code == 805 {
 			res.SubCode = 805000
-			if res.FailedReason != nil {
-				if strings.Contains(res.FailedReason.ExceptionMessage, "Porn") {
-					res.SubCode = 805001
-				}
-				if strings.Contains(res.FailedReason.ExceptionMessage, "显存告警") {
-					res.SubCode = 805002
-				}
-			}
 		}
+		res.classify(code == 805)
 		return res
 	}
+	res.classify(false)
 	if res.FailedReason != nil {
 		res.Msg = failReason.TraceBack
 		res.SignMsg = failReason.ExceptionMessage
 	}
 	return res
 }
+
+// classify 使用默认分类器对失败原因分类, withSubCode 为 true 时按规则设置子错误码
+func (e *ErrorInfo) classify(withSubCode bool) {
+	if e.FailedReason == nil {
+		return
+	}
+	classification := DefaultFailureClassifier().Classify(e.FailedReason)
+	e.Category = classification.Category
+	e.Retryable = classification.Retryable
+	e.Suggestion = classification.Message
+	if withSubCode && classification.SubCode != 0 {
+		e.SubCode = classification.SubCode
+	}
+}
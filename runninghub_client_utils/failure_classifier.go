@@ -0,0 +1,255 @@
+package runninghub_client_utils
+
+import (
+	"github.com/gogf/gf/v2/encoding/gjson"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"os"
+	"regexp"
+	"sync"
+)
+
+const (
+	FailureCategoryVramAlert         = "vram_alert"
+	FailureCategoryContentModeration = "content_moderation"
+	FailureCategoryCudaOOM           = "cuda_oom"
+	FailureCategoryMissingModel      = "missing_model"
+	FailureCategoryMissingCustomNode = "missing_custom_node"
+	FailureCategoryInputDecode       = "input_decode_error"
+	FailureCategoryTimeout           = "timeout"
+	FailureCategoryThirdPartyApi     = "third_party_api"
+	FailureCategoryUnknown           = "unknown"
+)
+
+// FailureRule 失败分类规则, 所有非空的匹配字段均为不区分大小写的正则, 需全部命中
+type FailureRule struct {
+	Name             string `json:"name"`
+	ExceptionType    string `json:"exceptionType"`
+	ExceptionMessage string `json:"exceptionMessage"`
+	TraceBack        string `json:"traceBack"`
+	NodeId           string `json:"nodeId"`
+	Any              string `json:"any"` // 命中 ExceptionType, ExceptionMessage, TraceBack 任意一个即可
+	Category         string `json:"category"`
+	Retryable        bool   `json:"retryable"`
+	Message          string `json:"message"` // 建议展示给用户的提示
+	SubCode          int    `json:"subCode"` // 对应 ErrorInfo.SubCode, 为 0 时不修改
+}
+
+// FailureClassification 分类结果
+type FailureClassification struct {
+	Rule      string `json:"rule"`
+	Category  string `json:"category"`
+	Retryable bool   `json:"retryable"`
+	Message   string `json:"message"`
+	SubCode   int    `json:"subCode"`
+}
+
+type compiledFailureRule struct {
+	rule             FailureRule
+	exceptionType    *regexp.Regexp
+	exceptionMessage *regexp.Regexp
+	traceBack        *regexp.Regexp
+	nodeId           *regexp.Regexp
+	any              *regexp.Regexp
+}
+
+// FailureClassifier 按规则表顺序匹配 FailedReason, 返回第一个命中的规则
+type FailureClassifier struct {
+	rules []*compiledFailureRule
+}
+
+// DefaultFailureRules 内置规则表
+func DefaultFailureRules() []FailureRule {
+	return []FailureRule{
+		{
+			Name:             "vram_alert",
+			ExceptionMessage: "显存告警",
+			Category:         FailureCategoryVramAlert,
+			Retryable:        true,
+			Message:          "显存告警, 请稍后重试或降低分辨率",
+			SubCode:          805002,
+		},
+		{
+			Name:      "content_moderation",
+			Any:       `porn|nsfw|sensitive content|content (moderation|policy)|违规|敏感`,
+			Category:  FailureCategoryContentModeration,
+			Retryable: false,
+			Message:   "内容未通过审核, 请修改输入后重试",
+			SubCode:   805001,
+		},
+		{
+			Name:      "cuda_oom",
+			Any:       `out of memory|OutOfMemoryError|allocation on device|CUBLAS_STATUS_ALLOC_FAILED`,
+			Category:  FailureCategoryCudaOOM,
+			Retryable: true,
+			Message:   "显存不足, 请降低分辨率或批量大小后重试",
+			SubCode:   805003,
+		},
+		{
+			Name:      "missing_custom_node",
+			Any:       `missing_node_type|node (type )?'?[\w.-]+'? (does not exist|not found)|cannot import .* module for custom nodes`,
+			Category:  FailureCategoryMissingCustomNode,
+			Retryable: false,
+			Message:   "工作流使用了平台不支持的自定义节点",
+			SubCode:   805005,
+		},
+		{
+			Name:      "missing_model",
+			Any:       `value not in list|(checkpoint|ckpt|model|lora|vae|unet|clip)\S*\s.*(not found|does not exist|no such file)|\.(safetensors|ckpt|pth?|gguf)\S*\s.*(not found|does not exist|no such file)|no such file.*\.(safetensors|ckpt|pth?|gguf)\b`,
+			Category:  FailureCategoryMissingModel,
+			Retryable: false,
+			Message:   "工作流引用的模型不存在, 请检查模型名称",
+			SubCode:   805004,
+		},
+		{
+			Name:      "input_decode",
+			Any:       `UnidentifiedImageError|cannot identify image file|image file is truncated|invalid image|failed to decode`,
+			Category:  FailureCategoryInputDecode,
+			Retryable: false,
+			Message:   "输入图片无法解析, 请检查图片格式",
+			SubCode:   805006,
+		},
+		{
+			Name:      "timeout",
+			Any:       `\btimed out\b|\btime ?outs?\b|timeout(error|exception)\b|deadline exceeded|超时`,
+			Category:  FailureCategoryTimeout,
+			Retryable: true,
+			Message:   "任务执行超时, 请稍后重试",
+			SubCode:   805007,
+		},
+		{
+			Name:      "third_party_api",
+			Any:       `third.?party|api (error|request failed)|status code:? ?(429|5\d\d)|too many requests`,
+			Category:  FailureCategoryThirdPartyApi,
+			Retryable: true,
+			Message:   "第三方接口调用失败, 请稍后重试",
+			SubCode:   805008,
+		},
+	}
+}
+
+// NewFailureClassifier 按顺序编译规则表
+func NewFailureClassifier(rules []FailureRule) (*FailureClassifier, error) {
+	c := &FailureClassifier{
+		rules: make([]*compiledFailureRule, 0, len(rules)),
+	}
+	for i, rule := range rules {
+		compiled := &compiledFailureRule{rule: rule}
+		for _, v := range []struct {
+			pattern string
+			target  **regexp.Regexp
+		}{
+			{rule.ExceptionType, &compiled.exceptionType},
+			{rule.ExceptionMessage, &compiled.exceptionMessage},
+			{rule.TraceBack, &compiled.traceBack},
+			{rule.NodeId, &compiled.nodeId},
+			{rule.Any, &compiled.any},
+		} {
+			if v.pattern == "" {
+				continue
+			}
+			re, err := regexp.Compile("(?i)" + v.pattern)
+			if err != nil {
+				return nil, gerror.Wrapf(err, "compile failure rule(%d: %s) fail", i, rule.Name)
+			}
+			*v.target = re
+		}
+		if rule.Category == "" {
+			return nil, gerror.Newf("failure rule(%d: %s) category cannot be empty", i, rule.Name)
+		}
+		c.rules = append(c.rules, compiled)
+	}
+	return c, nil
+}
+
+// NewFailureClassifierFromContent 从配置内容加载规则表, 支持 json/yaml/toml 等格式, 规则位于 rules 字段
+func NewFailureClassifierFromContent(content []byte) (*FailureClassifier, error) {
+	j, err := gjson.LoadContent(content)
+	if err != nil {
+		return nil, gerror.Wrap(err, "load failure rules fail")
+	}
+	var rules []FailureRule
+	if err = j.Get("rules").Scan(&rules); err != nil {
+		return nil, gerror.Wrap(err, "load failure rules fail")
+	}
+	return NewFailureClassifier(rules)
+}
+
+// LoadFailureClassifier 从配置文件加载规则表
+func LoadFailureClassifier(path string) (*FailureClassifier, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewFailureClassifierFromContent(content)
+}
+
+// Classify 返回第一个命中的规则, 均未命中时分类为 unknown
+func (c *FailureClassifier) Classify(reason *FailedReason) *FailureClassification {
+	if reason != nil {
+		for _, v := range c.rules {
+			if v.match(reason) {
+				return &FailureClassification{
+					Rule:      v.rule.Name,
+					Category:  v.rule.Category,
+					Retryable: v.rule.Retryable,
+					Message:   v.rule.Message,
+					SubCode:   v.rule.SubCode,
+				}
+			}
+		}
+	}
+	return &FailureClassification{
+		Category: FailureCategoryUnknown,
+	}
+}
+
+func (r *compiledFailureRule) match(reason *FailedReason) bool {
+	if r.exceptionType != nil && !r.exceptionType.MatchString(reason.ExceptionType) {
+		return false
+	}
+	if r.exceptionMessage != nil && !r.exceptionMessage.MatchString(reason.ExceptionMessage) {
+		return false
+	}
+	if r.traceBack != nil && !r.traceBack.MatchString(reason.TraceBack) {
+		return false
+	}
+	if r.nodeId != nil && !r.nodeId.MatchString(reason.NodeId) {
+		return false
+	}
+	if r.any != nil &&
+		!r.any.MatchString(reason.ExceptionType) &&
+		!r.any.MatchString(reason.ExceptionMessage) &&
+		!r.any.MatchString(reason.TraceBack) {
+		return false
+	}
+	return true
+}
+
+var (
+	defaultFailureClassifierMu sync.RWMutex
+	defaultFailureClassifier   *FailureClassifier
+)
+
+// DefaultFailureClassifier 返回 GetErrorInfo 使用的分类器
+func DefaultFailureClassifier() *FailureClassifier {
+	defaultFailureClassifierMu.RLock()
+	c := defaultFailureClassifier
+	defaultFailureClassifierMu.RUnlock()
+	if c != nil {
+		return c
+	}
+	defaultFailureClassifierMu.Lock()
+	defer defaultFailureClassifierMu.Unlock()
+	if defaultFailureClassifier == nil {
+		// 内置规则均为合法正则
+		defaultFailureClassifier, _ = NewFailureClassifier(DefaultFailureRules())
+	}
+	return defaultFailureClassifier
+}
+
+// SetDefaultFailureClassifier 替换 GetErrorInfo 使用的分类器
+func SetDefaultFailureClassifier(c *FailureClassifier) {
+	defaultFailureClassifierMu.Lock()
+	defer defaultFailureClassifierMu.Unlock()
+	defaultFailureClassifier = c
+}
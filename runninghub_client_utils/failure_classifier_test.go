@@ -0,0 +1,92 @@
+package runninghub_client_utils
+
+import (
+	"testing"
+)
+
+func TestDefaultFailureClassifier(t *testing.T) {
+	tests := []struct {
+		name   string
+		reason *FailedReason
+		want   string
+	}{
+		{name: "nil", want: FailureCategoryUnknown},
+		{name: "empty", reason: &FailedReason{}, want: FailureCategoryUnknown},
+		{name: "vram alert", reason: &FailedReason{ExceptionMessage: "显存告警"}, want: FailureCategoryVramAlert},
+		{name: "nsfw", reason: &FailedReason{ExceptionMessage: "NSFW content detected"}, want: FailureCategoryContentModeration},
+		{name: "porn", reason: &FailedReason{ExceptionType: "Porn"}, want: FailureCategoryContentModeration},
+		{name: "cuda oom", reason: &FailedReason{ExceptionType: "torch.OutOfMemoryError", ExceptionMessage: "CUDA out of memory. Tried to allocate 2.00 GiB"}, want: FailureCategoryCudaOOM},
+		{name: "cublas alloc", reason: &FailedReason{TraceBack: "RuntimeError: CUDA error: CUBLAS_STATUS_ALLOC_FAILED when calling cublasCreate(handle)"}, want: FailureCategoryCudaOOM},
+		{name: "missing node type", reason: &FailedReason{ExceptionType: "missing_node_type", ExceptionMessage: "Node 'ReActorFaceSwap' not found"}, want: FailureCategoryMissingCustomNode},
+		{name: "node does not exist", reason: &FailedReason{ExceptionMessage: "Cannot execute because node FooBar does not exist."}, want: FailureCategoryMissingCustomNode},
+		{name: "custom node import", reason: &FailedReason{TraceBack: "Cannot import /app/custom_nodes/foo module for custom nodes: No module named 'bar'"}, want: FailureCategoryMissingCustomNode},
+		{name: "value not in list", reason: &FailedReason{ExceptionMessage: "Value not in list: ckpt_name: 'sdxl.safetensors' not in ['v1-5.safetensors']"}, want: FailureCategoryMissingModel},
+		{name: "model not found in node", reason: &FailedReason{ExceptionMessage: "Node 12 model flux-dev.safetensors not found"}, want: FailureCategoryMissingModel},
+		{name: "lora file missing", reason: &FailedReason{ExceptionMessage: "lora_name my_style.safetensors does not exist"}, want: FailureCategoryMissingModel},
+		{name: "no such model file", reason: &FailedReason{ExceptionType: "FileNotFoundError", ExceptionMessage: "[Errno 2] No such file or directory: '/models/loras/a.safetensors'"}, want: FailureCategoryMissingModel},
+		{name: "input decode", reason: &FailedReason{ExceptionType: "PIL.UnidentifiedImageError", ExceptionMessage: "cannot identify image file <_io.BytesIO object>"}, want: FailureCategoryInputDecode},
+		{name: "truncated image", reason: &FailedReason{ExceptionMessage: "image file is truncated (3 bytes not processed)"}, want: FailureCategoryInputDecode},
+		{name: "timeout error", reason: &FailedReason{ExceptionType: "asyncio.TimeoutError"}, want: FailureCategoryTimeout},
+		{name: "read timed out", reason: &FailedReason{ExceptionMessage: "HTTPSConnectionPool: Read timed out. (read timeout=60)"}, want: FailureCategoryTimeout},
+		{name: "deadline exceeded", reason: &FailedReason{ExceptionMessage: "context deadline exceeded"}, want: FailureCategoryTimeout},
+		{name: "chinese timeout", reason: &FailedReason{ExceptionMessage: "任务执行超时"}, want: FailureCategoryTimeout},
+		{name: "time output is not a timeout", reason: &FailedReason{ExceptionMessage: "failed to save time output"}, want: FailureCategoryUnknown},
+		{name: "lifetime outlier is not a timeout", reason: &FailedReason{ExceptionMessage: "lifetime outlier index 3"}, want: FailureCategoryUnknown},
+		{name: "third party status", reason: &FailedReason{ExceptionMessage: "Third-party API error, status code: 503"}, want: FailureCategoryThirdPartyApi},
+		{name: "too many requests", reason: &FailedReason{ExceptionMessage: "429 Too Many Requests"}, want: FailureCategoryThirdPartyApi},
+		{name: "other", reason: &FailedReason{ExceptionType: "ValueError", ExceptionMessage: "invalid literal for int()"}, want: FailureCategoryUnknown},
+	}
+	classifier := DefaultFailureClassifier()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := classifier.Classify(tt.reason)
+			if res.Category != tt.want {
+				t.Fatalf("Classify() = %s (rule %q), want %s", res.Category, res.Rule, tt.want)
+			}
+			if tt.want != FailureCategoryUnknown && (res.SubCode == 0 || res.Message == "") {
+				t.Fatalf("Classify() = %+v, want sub code and message", res)
+			}
+		})
+	}
+}
+
+func TestFailureClassifierFromContent(t *testing.T) {
+	content := []byte(`
+rules:
+  - name: node_9_oom
+    nodeId: "^9$"
+    exceptionType: "OutOfMemory"
+    category: cuda_oom
+    retryable: true
+  - name: any_fallback
+    any: "boom"
+    category: custom
+`)
+	classifier, err := NewFailureClassifierFromContent(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		reason   *FailedReason
+		wantRule string
+	}{
+		{&FailedReason{NodeId: "9", ExceptionType: "OutOfMemoryError"}, "node_9_oom"},
+		// 所有非空字段均需命中
+		{&FailedReason{NodeId: "19", ExceptionType: "OutOfMemoryError"}, ""},
+		{&FailedReason{NodeId: "9", ExceptionType: "ValueError"}, ""},
+		{&FailedReason{NodeId: "9", TraceBack: "BOOM"}, "any_fallback"},
+	}
+	for _, tt := range tests {
+		if got := classifier.Classify(tt.reason); got.Rule != tt.wantRule {
+			t.Errorf("Classify(%+v) rule = %q, want %q", tt.reason, got.Rule, tt.wantRule)
+		}
+	}
+	for _, rules := range [][]FailureRule{
+		{{Name: "bad regex", Any: "(", Category: "x"}},
+		{{Name: "no category", Any: "x"}},
+	} {
+		if _, err = NewFailureClassifier(rules); err == nil {
+			t.Errorf("NewFailureClassifier(%+v) expected error", rules)
+		}
+	}
+}
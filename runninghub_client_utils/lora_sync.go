@@ -0,0 +1,229 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Friday-fighting/runninghub_tools/utility/hash"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const defaultLoraManifestName = ".runninghub_lora_manifest.json"
+
+// LoraKeyFromPath 由相对路径生成合法的 LoRA 名称, 非 a-z, A-Z, 0-9 的字符替换为 _
+func LoraKeyFromPath(relPath string) string {
+	relPath = strings.TrimSuffix(relPath, filepath.Ext(relPath))
+	var b strings.Builder
+	lastUnderscore := false
+	for _, c := range relPath {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			b.WriteRune(c)
+			lastUnderscore = false
+			continue
+		}
+		if !lastUnderscore {
+			b.WriteRune('_')
+			lastUnderscore = true
+		}
+	}
+	key := strings.Trim(b.String(), "_")
+	if key == "" {
+		key = "lora"
+	}
+	return key
+}
+
+func loraManifestKey(relPath string) string {
+	return "file:" + filepath.ToSlash(relPath)
+}
+
+// SyncLoraDir 将本地 LoRA 目录同步到 RunningHub, 只上传新增或内容变化的文件, 并维护本地清单;
+// 清单按相对路径记录, 文件的 LoRA 名称一经分配不再改变
+func (c *RunningHubClient) SyncLoraDir(ctx context.Context, in *SyncLoraDirInput) (res *SyncLoraDirRes, err error) {
+	if in == nil || in.Dir == "" {
+		return nil, gerror.New("lora dir cannot be empty")
+	}
+	if in.ManifestPath == "" {
+		in.ManifestPath = filepath.Join(in.Dir, defaultLoraManifestName)
+	}
+	if len(in.Extensions) == 0 {
+		in.Extensions = []string{".safetensors"}
+	}
+	manifest, err := NewFileStore(in.ManifestPath)
+	if err != nil {
+		return nil, gerror.Wrap(err, "open lora manifest fail")
+	}
+	records, err := loadLoraManifest(ctx, manifest)
+	if err != nil {
+		return nil, gerror.Wrap(err, "load lora manifest fail")
+	}
+	items, err := scanLoraDir(in.Dir, in.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	assignLoraNames(items, records)
+	wanted := make(map[string]bool, len(items))
+	for _, v := range items {
+		wanted[filepath.ToSlash(v.RelPath)] = true
+	}
+	hashes, err := hash.Dir(ctx, in.Dir, &hash.DirOption{
+		Concurrency: in.Concurrency,
+		Filter:      func(relPath string) bool { return wanted[relPath] },
+	})
+	if err != nil {
+		return nil, err
+	}
+	md5HexMap := make(map[string]string, len(hashes.Files))
+	for _, v := range hashes.Files {
+		md5HexMap[v.Path] = v.Md5Hex
+	}
+	res = &SyncLoraDirRes{
+		ManifestPath: in.ManifestPath,
+		DryRun:       in.DryRun,
+		Items:        items,
+	}
+	for _, v := range items {
+		if err = ctx.Err(); err != nil {
+			return res, err
+		}
+		v.Md5Hex = md5HexMap[filepath.ToSlash(v.RelPath)]
+		record, ok := records[v.RelPath]
+		switch {
+		case !ok:
+			v.Action = LoraSyncActionNew
+		case record.Md5Hex != v.Md5Hex || record.Size != v.Size:
+			v.Action = LoraSyncActionChanged
+		default:
+			v.Action = LoraSyncActionUnchanged
+			v.Record = record
+			continue
+		}
+		if in.DryRun {
+			continue
+		}
+		var progress func(done, total int64)
+		if in.Progress != nil {
+			loraName := v.LoraName
+			progress = func(done, total int64) {
+				in.Progress(loraName, done, total)
+			}
+		}
+		v.Record, v.Err = c.UploadLoraFileWithOption(ctx, &UploadLoraOption{
+			LoraName: v.LoraName,
+			FilePath: v.LocalPath,
+			Md5Hex:   v.Md5Hex,
+			Progress: progress,
+			Force:    true,
+		})
+		if v.Err == nil {
+			v.Err = saveLoraManifestRecord(ctx, manifest, v.RelPath, v.Record)
+		}
+		v.Uploaded = v.Err == nil
+	}
+	for _, v := range items {
+		if v.Err != nil {
+			return res, gerror.Wrapf(v.Err, "sync lora(%s) fail", v.LocalPath)
+		}
+	}
+	return res, nil
+}
+
+// loadLoraManifest 读取清单, 返回相对路径到上传记录的映射
+func loadLoraManifest(ctx context.Context, manifest Store) (records map[string]*LoraRecord, err error) {
+	records = make(map[string]*LoraRecord)
+	var decodeErr error
+	err = manifest.Scan(ctx, "file:", func(key string, value []byte) bool {
+		var record *LoraRecord
+		if decodeErr = json.Unmarshal(value, &record); decodeErr != nil {
+			return false
+		}
+		if record != nil {
+			records[filepath.FromSlash(strings.TrimPrefix(key, "file:"))] = record
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, decodeErr
+}
+
+func saveLoraManifestRecord(ctx context.Context, manifest Store, relPath string, record *LoraRecord) error {
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return manifest.Set(ctx, loraManifestKey(relPath), value)
+}
+
+// assignLoraNames 清单中已有的文件沿用原名称, 新文件由相对路径生成名称, 与已用名称冲突时追加序号
+func assignLoraNames(items []*SyncLoraItem, records map[string]*LoraRecord) {
+	usedNames := make(map[string]struct{}, len(records))
+	for _, record := range records {
+		usedNames[record.LoraName] = struct{}{}
+	}
+	for _, v := range items {
+		if record, ok := records[v.RelPath]; ok {
+			v.LoraName = record.LoraName
+		}
+	}
+	for _, v := range items {
+		if v.LoraName != "" {
+			continue
+		}
+		key := LoraKeyFromPath(v.RelPath)
+		name := key
+		for n := 2; ; n++ {
+			if _, ok := usedNames[name]; !ok {
+				break
+			}
+			name = fmt.Sprintf("%s_%d", key, n)
+		}
+		usedNames[name] = struct{}{}
+		v.LoraName = name
+	}
+}
+
+// scanLoraDir 按相对路径顺序扫描目录下的 LoRA 文件
+func scanLoraDir(dir string, extensions []string) (items []*SyncLoraItem, err error) {
+	extMap := make(map[string]struct{}, len(extensions))
+	for _, ext := range extensions {
+		extMap[strings.ToLower(ext)] = struct{}{}
+	}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if _, ok := extMap[strings.ToLower(filepath.Ext(path))]; !ok {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		items = append(items, &SyncLoraItem{
+			LocalPath: path,
+			RelPath:   relPath,
+			Size:      info.Size(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].RelPath < items[j].RelPath
+	})
+	return items, nil
+}
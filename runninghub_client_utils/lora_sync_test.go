@@ -0,0 +1,78 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoraKeyFromPath(t *testing.T) {
+	tests := map[string]string{
+		"style.safetensors":            "style",
+		"sd/my-style v2.safetensors":   "sd_my_style_v2",
+		"__a__.safetensors":            "a",
+		"中文.safetensors":               "lora",
+		filepath.Join("a", "b", "c.x"): "a_b_c",
+	}
+	for in, want := range tests {
+		if got := LoraKeyFromPath(in); got != want {
+			t.Errorf("LoraKeyFromPath(%q) = %q, want %q", in, got, want)
+		}
+		if err := ValidateLoraKey(LoraKeyFromPath(in)); err != nil {
+			t.Errorf("LoraKeyFromPath(%q): %v", in, err)
+		}
+	}
+}
+
+func TestSyncLoraDirKeepsNames(t *testing.T) {
+	fake := &fakeLoraServer{}
+	client := newTestClient(t, fake)
+	fake.url = client.url
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sync := func(dryRun bool) map[string]*SyncLoraItem {
+		res, err := client.SyncLoraDir(context.Background(), &SyncLoraDirInput{Dir: dir, DryRun: dryRun})
+		if err != nil {
+			t.Fatal(err)
+		}
+		items := make(map[string]*SyncLoraItem)
+		for _, v := range res.Items {
+			items[v.RelPath] = v
+		}
+		return items
+	}
+
+	write("a_x.safetensors", "one")
+	if items := sync(false); items["a_x.safetensors"].LoraName != "a_x" || !items["a_x.safetensors"].Uploaded {
+		t.Fatalf("first sync = %+v", items["a_x.safetensors"])
+	}
+	// 新文件的名称与已有文件冲突, 且排序在前
+	write("a-x.safetensors", "two")
+	if items := sync(true); items["a-x.safetensors"].LoraName != "a_x_2" || items["a_x.safetensors"].Action != LoraSyncActionUnchanged {
+		t.Fatalf("dry run = %+v, %+v", items["a-x.safetensors"], items["a_x.safetensors"])
+	}
+	if got := fake.putCount(); got != 1 {
+		t.Fatalf("puts after dry run = %d, want 1", got)
+	}
+	items := sync(false)
+	if items["a_x.safetensors"].LoraName != "a_x" || items["a_x.safetensors"].Uploaded {
+		t.Fatalf("existing file = %+v", items["a_x.safetensors"])
+	}
+	if items["a-x.safetensors"].LoraName != "a_x_2" || !items["a-x.safetensors"].Uploaded {
+		t.Fatalf("new file = %+v", items["a-x.safetensors"])
+	}
+	// 内容变化时沿用原名称重新上传
+	write("a_x.safetensors", "three")
+	items = sync(false)
+	if v := items["a_x.safetensors"]; v.LoraName != "a_x" || v.Action != LoraSyncActionChanged || !v.Uploaded {
+		t.Fatalf("changed file = %+v", v)
+	}
+	if got := fake.putCount(); got != 3 {
+		t.Fatalf("puts = %d, want 3", got)
+	}
+}
@@ -0,0 +1,214 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Friday-fighting/runninghub_tools/utility/hash"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ValidateLoraKey 校验 LoRA 名称, 只能包含 a-z, A-Z, 0-9 或 _
+func ValidateLoraKey(key string) error {
+	if key == "" {
+		return gerror.New("key must be not empty")
+	}
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' ||
+			c >= 'A' && c <= 'Z' ||
+			c == '_' ||
+			c >= '0' && c <= '9') {
+			return gerror.Newf("key(%s) must be  a-z, A-Z, 0-9 or _", key)
+		}
+	}
+	return nil
+}
+
+func loraRecordKey(loraName string) string {
+	return "lora:" + loraName
+}
+
+// GetLoraRecord 读取本地 LoRA 上传记录
+func GetLoraRecord(ctx context.Context, store Store, loraName string) (record *LoraRecord, ok bool, err error) {
+	if store == nil {
+		return nil, false, nil
+	}
+	value, ok, err := store.Get(ctx, loraRecordKey(loraName))
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	if err = json.Unmarshal(value, &record); err != nil {
+		return nil, false, err
+	}
+	return record, record != nil, nil
+}
+
+// SaveLoraRecord 保存本地 LoRA 上传记录
+func SaveLoraRecord(ctx context.Context, store Store, record *LoraRecord) error {
+	if store == nil || record == nil {
+		return nil
+	}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return store.Set(ctx, loraRecordKey(record.LoraName), value)
+}
+
+// NodeInfo 生成在工作流节点中引用该 LoRA 的 NodeInfo
+func (r *LoraRecord) NodeInfo(nodeId string, fieldName string) *NodeInfo {
+	value := r.FileName
+	if value == "" {
+		value = r.LoraName
+	}
+	return &NodeInfo{
+		NodeId:     nodeId,
+		FieldName:  fieldName,
+		FieldValue: value,
+	}
+}
+
+// UploadLoraFileWithOption 流式上传 LoRA 文件, 支持进度回调、失败重试和 ctx 取消;
+// 本地记录中名称与 MD5 均一致时跳过上传。上传地址为预签名的单次 PUT, 平台不支持分片与断点续传, 每次重试都从头上传整个文件,
+// 上传地址被拒绝(401/403, 通常为预签名过期)时重新获取地址; 响应的 ETag 为 MD5 时与本地 MD5 比对
+func (c *RunningHubClient) UploadLoraFileWithOption(ctx context.Context, in *UploadLoraOption) (record *LoraRecord, err error) {
+	if in == nil {
+		return nil, gerror.New("upload lora option cannot be nil")
+	}
+	if err = ValidateLoraKey(in.LoraName); err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(in.FilePath)
+	if err != nil || stat.IsDir() {
+		return nil, gerror.Newf("filePath(%s) does not point to a file", in.FilePath)
+	}
+	if in.Md5Hex == "" {
+		sum, err := hash.File(ctx, in.FilePath, nil)
+		if err != nil {
+			return nil, err
+		}
+		in.Md5Hex = sum.Md5Hex
+	}
+	if in.MaxRetries == 0 {
+		in.MaxRetries = 3
+	}
+	if in.RetryInterval <= 0 {
+		in.RetryInterval = 2 * time.Second
+	}
+	if in.MaxInterval <= 0 {
+		in.MaxInterval = 30 * time.Second
+	}
+	if !in.Force {
+		record, ok, err := GetLoraRecord(ctx, in.RecordStore, in.LoraName)
+		if err != nil {
+			return nil, err
+		}
+		if ok && record.Md5Hex == in.Md5Hex && record.Size == stat.Size() {
+			record.Skipped = true
+			return record, nil
+		}
+	}
+	uploadUrl, err := c.GetLoraUploadUrl(ctx, in.LoraName, in.Md5Hex)
+	if err != nil {
+		return nil, err
+	}
+	interval := in.RetryInterval
+	for attempt := 0; ; attempt++ {
+		err = c.putFile(ctx, uploadUrl.Url, in.FilePath, stat.Size(), in.Md5Hex, in.Progress)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil || attempt >= in.MaxRetries {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		interval = min(interval*2, in.MaxInterval)
+		var statusErr *loraPutStatusError
+		if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden) {
+			if uploadUrl, err = c.GetLoraUploadUrl(ctx, in.LoraName, in.Md5Hex); err != nil {
+				return nil, err
+			}
+		}
+	}
+	record = &LoraRecord{
+		LoraName:   in.LoraName,
+		FileName:   uploadUrl.FileName,
+		Md5Hex:     in.Md5Hex,
+		Size:       stat.Size(),
+		UploadUrl:  uploadUrl.Url,
+		LocalPath:  in.FilePath,
+		UploadedAt: time.Now(),
+	}
+	if err = SaveLoraRecord(ctx, in.RecordStore, record); err != nil {
+		return record, gerror.Wrap(err, "save lora record fail")
+	}
+	return record, nil
+}
+
+// putFile 以 PUT 方式流式上传文件, 响应的 ETag 为 MD5 时校验上传内容
+func (c *RunningHubClient) putFile(ctx context.Context, url string, filePath string, size int64, md5Hex string, progress func(done, total int64)) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var body io.Reader = file
+	if progress != nil {
+		body = &progressReader{r: file, total: size, progress: progress}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Authorization", "Bearer "+c.ApiKey)
+	// 大文件上传耗时不定, 只受 ctx 控制
+	httpClient := c.httpClient.Client
+	httpClient.Timeout = 0
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 检查响应状态码
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return &loraPutStatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	if etag := strings.Trim(resp.Header.Get("ETag"), `"`); isMd5Hex(etag) && !strings.EqualFold(etag, md5Hex) {
+		return gerror.Newf("uploaded file md5 mismatch, etag: %s, md5: %s", etag, md5Hex)
+	}
+	return nil
+}
+
+// loraPutStatusError 上传地址返回的非 200 响应
+type loraPutStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *loraPutStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code, respCode: %d, respBody: %v", e.StatusCode, e.Body)
+}
+
+// isMd5Hex 判断是否为 32 位十六进制字符串; 分片上传的 ETag 带有 "-n" 后缀, 不是文件 MD5
+func isMd5Hex(s string) bool {
+	if len(s) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
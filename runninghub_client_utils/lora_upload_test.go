@@ -0,0 +1,170 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLoraServer 模拟获取上传地址与预签名 PUT 接口
+type fakeLoraServer struct {
+	mu       sync.Mutex
+	url      string
+	etag     string // PUT 响应的 ETag, 为空时返回上传内容的 MD5
+	failures int    // 之后的 PUT 返回 failCode 的次数
+	failCode int    // 默认 500
+	urls     int    // 获取上传地址的次数
+	puts     int
+	body     []byte
+}
+
+func (f *fakeLoraServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case getLoraUploadUrl:
+		f.mu.Lock()
+		f.urls++
+		f.mu.Unlock()
+		writeTestResponse(w, 0, "success", &UploadLoraFileRes{FileName: "lora/abc.safetensors", Url: f.url + "/put?signature=x"})
+	case "/put":
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.puts++
+		if f.failures > 0 {
+			f.failures--
+			if f.failCode == 0 {
+				f.failCode = http.StatusInternalServerError
+			}
+			w.WriteHeader(f.failCode)
+			return
+		}
+		f.body = body
+		etag := f.etag
+		if etag == "" {
+			sum := md5.Sum(body)
+			etag = hex.EncodeToString(sum[:])
+		}
+		w.Header().Set("ETag", `"`+etag+`"`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeLoraServer) putCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.puts
+}
+
+func (f *fakeLoraServer) urlCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.urls
+}
+
+func TestUploadLoraFileWithOption(t *testing.T) {
+	content := []byte(strings.Repeat("lora", 1000))
+	tests := []struct {
+		name       string
+		etag       string
+		failures   int
+		failCode   int
+		maxRetries int
+		wantPuts   int
+		wantUrls   int
+		wantErr    bool
+	}{
+		{name: "ok", wantPuts: 1, wantUrls: 1},
+		{name: "multipart etag is not checked", etag: "0123456789abcdef-2", wantPuts: 1, wantUrls: 1},
+		{name: "retry server error", failures: 2, wantPuts: 3, wantUrls: 1},
+		// 预签名地址过期时重新获取地址
+		{name: "refresh expired url", failures: 2, failCode: http.StatusForbidden, wantPuts: 3, wantUrls: 3},
+		{name: "no retry", failures: 1, maxRetries: -1, wantPuts: 1, wantUrls: 1, wantErr: true},
+		{name: "etag mismatch", etag: strings.Repeat("0", 32), maxRetries: -1, wantPuts: 1, wantUrls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeLoraServer{etag: tt.etag, failures: tt.failures, failCode: tt.failCode}
+			client := newTestClient(t, fake)
+			fake.url = client.url
+			filePath := filepath.Join(t.TempDir(), "a.safetensors")
+			if err := os.WriteFile(filePath, content, os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			store := NewMemoryStore()
+			var lastDone int64
+			record, err := client.UploadLoraFileWithOption(context.Background(), &UploadLoraOption{
+				LoraName:      "my_lora",
+				FilePath:      filePath,
+				MaxRetries:    tt.maxRetries,
+				RetryInterval: time.Millisecond,
+				Progress:      func(done, total int64) { lastDone = done },
+				RecordStore:   store,
+			})
+			if got := fake.putCount(); got != tt.wantPuts {
+				t.Fatalf("puts = %d, want %d", got, tt.wantPuts)
+			}
+			if got := fake.urlCount(); got != tt.wantUrls {
+				t.Fatalf("upload urls = %d, want %d", got, tt.wantUrls)
+			}
+			_, saved, _ := GetLoraRecord(context.Background(), store, "my_lora")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				if saved {
+					t.Fatal("record saved after failed upload")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(fake.body) != string(content) || lastDone != int64(len(content)) {
+				t.Fatalf("uploaded %d bytes, progress %d", len(fake.body), lastDone)
+			}
+			if record.FileName != "lora/abc.safetensors" || record.Size != int64(len(content)) {
+				t.Fatalf("record = %+v", record)
+			}
+			if node := record.NodeInfo("1", "lora_name"); node.FieldValue != record.FileName {
+				t.Fatalf("NodeInfo() = %+v", node)
+			}
+			// 本地记录一致时跳过上传, 记录中不保存预签名地址
+			again, err := client.UploadLoraFileWithOption(context.Background(), &UploadLoraOption{
+				LoraName:    "my_lora",
+				FilePath:    filePath,
+				RecordStore: store,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !again.Skipped || again.UploadUrl != "" || fake.putCount() != tt.wantPuts {
+				t.Fatalf("second upload = %+v, puts = %d", again, fake.putCount())
+			}
+		})
+	}
+}
+
+func TestUploadLoraFileSingleAttempt(t *testing.T) {
+	fake := &fakeLoraServer{failures: 1}
+	client := newTestClient(t, fake)
+	fake.url = client.url
+	filePath := filepath.Join(t.TempDir(), "a.safetensors")
+	if err := os.WriteFile(filePath, []byte("lora"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.UploadLoraFile(context.Background(), "my_lora", "md5", filePath); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := fake.putCount(); got != 1 {
+		t.Fatalf("puts = %d, want 1", got)
+	}
+}
@@ -2,6 +2,8 @@ package runninghub_client_utils
 
 import (
 	"encoding/json"
+	"github.com/Friday-fighting/runninghub_tools/utility"
+	"io"
 	"time"
 )
 
@@ -10,6 +12,11 @@ type RunningHubClientConfig struct {
 	Host       string `json:"host"`
 	ApiKey     string `json:"api_key"`
 	Timeout    time.Duration
+	// UploadCache 上传去重缓存, 为空时不启用; 按 host 与 api key 隔离, 可由多个客户端共用.
+	// 仅对内存与本地文件上传生效, 链接与 Reader 上传不查询缓存
+	UploadCache *UploadCache `json:"-"`
+	// Lang 错误提示语言, 如 zh-CN, en-US, 默认 zh-CN
+	Lang string `json:"lang"`
 }
 
 // RunningHubResponse RunningHub 通用响应结构
@@ -20,11 +27,49 @@ type RunningHubResponse struct {
 	Data          json.RawMessage `json:"data"`
 }
 
+const (
+	InstanceTypeDefault = ""     // 默认机型
+	InstanceTypePlus    = "plus" // plus 机型(48G 显存)
+)
+
 type CreateTaskReq struct {
-	WorkflowId   string      `json:"workflowId"`
+	WorkflowId       string      `json:"workflowId"`
+	NodeInfoList     []*NodeInfo `json:"nodeInfoList"`
+	ApiKey           string      `json:"apiKey"`
+	WebhookUrl       string      `json:"webhookUrl"`
+	InstanceType     string      `json:"instanceType,omitempty"`     // 机型, 为空时使用默认机型
+	UsePersonalQueue bool        `json:"usePersonalQueue,omitempty"` // 独占型账户使用个人队列排队, 不因实例不足直接失败
+	AddMetadata      bool        `json:"addMetadata,omitempty"`      // 在输出图片中写入工作流元数据
+	Workflow         string      `json:"workflow,omitempty"`         // 完整的工作流 API 格式 JSON 字符串, 不为空时替代平台保存的工作流, 通过 SetWorkflow 设置
+}
+
+// CreateWebAppTaskReq 通过 AI 应用创建任务
+type CreateWebAppTaskReq struct {
+	WebappId     string      `json:"webappId"`
 	NodeInfoList []*NodeInfo `json:"nodeInfoList"`
 	ApiKey       string      `json:"apiKey"`
-	WebhookUrl   string      `json:"webhookUrl"`
+	WebhookUrl   string      `json:"webhookUrl,omitempty"`
+	InstanceType string      `json:"instanceType,omitempty"` // 机型, 为空时使用默认机型
+}
+
+// WebAppNodeInfo AI 应用可修改的输入节点
+type WebAppNodeInfo struct {
+	NodeId        string `json:"nodeId"`
+	NodeName      string `json:"nodeName"`
+	FieldName     string `json:"fieldName"`
+	FieldValue    string `json:"fieldValue"`
+	FieldData     string `json:"fieldData"` // 下拉等类型的可选值, 原始 JSON 字符串
+	FieldType     string `json:"fieldType"` // IMAGE, AUDIO, VIDEO, STRING, LIST 等
+	Description   string `json:"description"`
+	DescriptionEn string `json:"descriptionEn"`
+}
+
+type GetWebAppInfoRes struct {
+	WebappName   string            `json:"webappName"`
+	NodeInfoList []*WebAppNodeInfo `json:"nodeInfoList"`
+	Covers       []struct {
+		Url string `json:"url"`
+	} `json:"covers"`
 }
 
 type NodeInfo struct {
@@ -66,7 +111,7 @@ type GetTaskStatusAndResultReqWithRetry struct {
 }
 
 type GetTaskStatusAndResultRes struct {
-	Status       string                                `json:"status"`
+	Status       TaskStatus                            `json:"status"`
 	Code         int                                   `json:"code"`
 	Msg          string                                `json:"msg"`
 	SuccessItems []*SuccessOfGetTaskResultResponseData `json:"success_items"`
@@ -119,11 +164,34 @@ type WorkflowJSONNodeInfo struct {
 	Meta      map[string]string      `json:"_meta"`
 }
 
+const (
+	UploadModeBytes = 0 // 内存
+	UploadModeFile  = 1 // 文件
+	UploadModeUrl   = 2 // 链接
+)
+
 type FileSource struct {
 	UploadMode int    // 0: 内存 1: 文件 2: 链接
 	Path       string // 如果走文件
 	Bytes      []byte // 如果走内存
 	Url        string // 如果走链接
+	FileName   string // 上传使用的文件名, 为空时取路径或链接最后一截, 内存资源按内容识别扩展名
+}
+
+type UploadSourceOption struct {
+	UseV2      bool                           // 使用 v2 方式上传, 返回下载链接
+	Progress   func(done, total int64)        // 上传进度回调
+	Preprocess *utility.ImagePreprocessOption // 上传前的图片预处理, 为空时不处理
+}
+
+// UploadSourceRes 统一的上传结果
+type UploadSourceRes struct {
+	FileName    string `json:"fileName"`
+	DownloadUrl string `json:"downloadUrl"` // 仅 v2 方式返回
+	Size        int64  `json:"size"`        // 未知时为 -1
+	Type        string `json:"type"`
+	Width       int    `json:"width"`  // 仅预处理后返回
+	Height      int    `json:"height"` // 仅预处理后返回
 }
 
 type UploadResourceRes struct {
@@ -154,4 +222,130 @@ type WorkflowNodeInfo struct {
 	NodeType  string `json:"nodeType"`
 	NodeId    string `json:"nodeId"`
 	FieldName string `json:"fieldName"`
+	Title     string `json:"title"` // 节点 _meta.title
+}
+
+type DownloadTaskOutputsOption struct {
+	TaskId       string        // 用于文件名模板中的 {taskId}
+	NameTemplate string        // 文件名模板, 默认 {taskId}_{nodeId}_{index}.{ext}
+	Concurrency  int           // 并行下载数, 默认 4
+	Timeout      time.Duration // 单个文件下载超时, 默认 60s
+}
+
+type DownloadTaskOutputItem struct {
+	Index       int    `json:"index"`
+	NodeId      string `json:"nodeId"`
+	FileUrl     string `json:"fileUrl"`
+	FileType    string `json:"fileType"`
+	LocalPath   string `json:"localPath"`
+	SidecarPath string `json:"sidecarPath"`
+	Size        int64  `json:"size"`
+	Skipped     bool   `json:"skipped"` // 本地已存在且校验通过, 未重新下载
+	Err         error  `json:"-"`
+}
+
+type DownloadTaskOutputsRes struct {
+	Items []*DownloadTaskOutputItem `json:"items"`
+}
+
+// TaskOutputSidecar 输出文件旁的 JSON 元数据
+type TaskOutputSidecar struct {
+	TaskId                 string  `json:"taskId"`
+	NodeId                 string  `json:"nodeId"`
+	Index                  int     `json:"index"`
+	FileUrl                string  `json:"fileUrl"`
+	FileType               string  `json:"fileType"`
+	FileName               string  `json:"fileName"`
+	Size                   int64   `json:"size"`
+	TaskCostTime           string  `json:"taskCostTime"`
+	ConsumeMoney           string  `json:"consumeMoney"`
+	ConsumeCoins           *string `json:"consumeCoins"`
+	ThirdPartyConsumeMoney *string `json:"thirdPartyConsumeMoney"`
+	DownloadedAt           string  `json:"downloadedAt"`
+	DownloadCostMs         int64   `json:"downloadCostMs"`
+}
+
+// UploadStreamInput 流式上传参数
+type UploadStreamInput struct {
+	FileName    string                  // 上传使用的文件名
+	ContentType string                  // 为空时按扩展名推断
+	Reader      io.Reader               // 文件内容
+	Size        int64                   // 内容长度, 未知时传 -1
+	Progress    func(done, total int64) // 上传进度回调, total 未知时为 -1
+	Timeout     time.Duration           // 整个请求的超时, 为 0 时不限制, 由 ctx 控制
+}
+
+type UploadLoraOption struct {
+	LoraName      string                  // 只能包含 a-z, A-Z, 0-9 或 _
+	FilePath      string                  // 本地 LoRA 文件
+	Md5Hex        string                  // 为空时自动计算
+	MaxRetries    int                     // 上传失败重试次数, 默认 3, 小于 0 不重试
+	RetryInterval time.Duration           // 首次重试间隔, 之后指数退避, 默认 2s
+	MaxInterval   time.Duration           // 重试间隔上限, 默认 30s
+	Progress      func(done, total int64) // 上传进度回调
+	RecordStore   Store                   // 本地上传记录, 为空时不做比对
+	Force         bool                    // 忽略本地记录强制上传
+}
+
+// LoraRecord LoRA 上传记录
+type LoraRecord struct {
+	LoraName   string    `json:"loraName"`
+	FileName   string    `json:"fileName"` // 平台返回的文件名, 用于节点中引用
+	Md5Hex     string    `json:"md5Hex"`
+	Size       int64     `json:"size"`
+	LocalPath  string    `json:"localPath"`
+	UploadedAt time.Time `json:"uploadedAt"`
+	UploadUrl  string    `json:"-"` // 本次上传使用的预签名地址, 会过期, 不是 LoRA 的资源地址, 不写入记录
+	Skipped    bool      `json:"-"` // 本地记录一致, 本次未上传
+}
+
+type SyncLoraDirInput struct {
+	Dir          string                                   // 本地 LoRA 目录, 递归扫描
+	ManifestPath string                                   // 清单文件, 默认 Dir/.runninghub_lora_manifest.json
+	Extensions   []string                                 // 文件扩展名, 默认 .safetensors
+	Concurrency  int                                      // 并行计算 MD5 数, 默认 4
+	DryRun       bool                                     // 只比对不上传
+	Progress     func(loraName string, done, total int64) // 上传进度回调
+}
+
+const (
+	LoraSyncActionNew       = "new"
+	LoraSyncActionChanged   = "changed"
+	LoraSyncActionUnchanged = "unchanged"
+)
+
+type SyncLoraItem struct {
+	LocalPath string      `json:"localPath"`
+	RelPath   string      `json:"relPath"` // 相对 Dir 的路径, 清单的键
+	LoraName  string      `json:"loraName"`
+	Md5Hex    string      `json:"md5Hex"`
+	Size      int64       `json:"size"`
+	Action    string      `json:"action"` // new, changed, unchanged
+	Uploaded  bool        `json:"uploaded"`
+	Record    *LoraRecord `json:"record"`
+	Err       error       `json:"-"`
+}
+
+type SyncLoraDirRes struct {
+	ManifestPath string          `json:"manifestPath"`
+	DryRun       bool            `json:"dryRun"`
+	Items        []*SyncLoraItem `json:"items"`
+}
+
+type Base64InputOption struct {
+	Preprocess      *utility.ImagePreprocessOption // 编码前的图片预处理, 可用于缩小尺寸
+	MaxPayloadBytes int                            // base64 字符串长度超过该值时告警, 设置 Preprocess 时按编码后长度换算压缩目标, 0 不检查
+	Warn            func(msg string)               // 告警回调, 为空时写入默认日志
+}
+
+// MediaInput 待绑定到图片输入节点的资源
+type MediaInput struct {
+	Name   string      // 按节点 _meta.title 或节点 id 绑定, 为空时按节点顺序绑定
+	Source *FileSource // 资源
+}
+
+type BindPictureInputsOption struct {
+	UseV2      bool                           // file 节点的上传方式
+	Preprocess *utility.ImagePreprocessOption // 上传前的图片预处理
+	Base64     *Base64InputOption             // base64 节点的编码参数
 }
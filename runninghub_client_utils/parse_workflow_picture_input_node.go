@@ -1,5 +1,11 @@
 package runninghub_client_utils
 
+const (
+	NodeTypeBase64 = "base64"
+	NodeTypeFile   = "file"
+	NodeTypeUrl    = "url"
+)
+
 type NodeMeta struct {
 	ClassType string `json:"class_type"`
 	FieldName string `json:"field_name"`
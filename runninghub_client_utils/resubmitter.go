@@ -0,0 +1,298 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/util/gconv"
+	"math/rand"
+	"time"
+)
+
+const (
+	ResubmitActionRetry   = "retry"    // 原样重新提交
+	ResubmitActionDelay   = "delay"    // 等待后重新提交
+	ResubmitActionNewSeed = "new_seed" // 更换随机种子后重新提交
+	ResubmitActionGiveUp  = "give_up"  // 放弃
+)
+
+// DefaultResubmitActions 默认的失败分类处理方式
+func DefaultResubmitActions() map[string]string {
+	return map[string]string{
+		FailureCategoryVramAlert:         ResubmitActionRetry,
+		FailureCategoryCudaOOM:           ResubmitActionRetry,
+		FailureCategoryTimeout:           ResubmitActionRetry,
+		FailureCategoryThirdPartyApi:     ResubmitActionDelay,
+		FailureCategoryContentModeration: ResubmitActionGiveUp, // 重复提交违规内容会产生费用并可能导致账号受限
+		FailureCategoryMissingModel:      ResubmitActionGiveUp,
+		FailureCategoryMissingCustomNode: ResubmitActionGiveUp,
+		FailureCategoryInputDecode:       ResubmitActionGiveUp,
+		FailureCategoryUnknown:           ResubmitActionGiveUp,
+	}
+}
+
+// DefaultResubmitCodeActions 默认的错误码处理方式, 优先于失败分类
+func DefaultResubmitCodeActions() map[int]string {
+	return map[int]string{
+		415: ResubmitActionDelay, // 独占型 API 实例不足
+		421: ResubmitActionDelay, // 共享型 API 并发已满
+	}
+}
+
+type ResubmitterConfig struct {
+	Client         *RunningHubClient
+	Classifier     *FailureClassifier // 为空时使用默认分类器
+	TaskStore      *TaskStore         // 为空时不记录
+	Actions        map[string]string  // 失败分类 -> 处理方式, 为空时使用默认值
+	CodeActions    map[int]string     // 错误码 -> 处理方式, 为空时使用默认值
+	MaxAttempts    int                // 包括首次提交在内的最大提交次数, 默认 3
+	Delay          time.Duration      // delay 方式的等待时间, 默认 30s
+	PollInterval   time.Duration      // 等待任务结束的轮询间隔, 默认 5s
+	SeedFieldNames []string           // new_seed 方式修改的字段名, 默认 seed, noise_seed
+	// RetryNonRetryable 命中 Retryable=false 的分类规则时仍按 Actions 处理, 默认直接放弃
+	RetryNonRetryable bool
+	// HandleOption 不为空时每次提交都通过 StartTask 绑定 ctx, ctx 取消且宽限期内未结束时取消远程任务
+	HandleOption *TaskHandleOption
+}
+
+// ResubmitAttempt 单次提交结果
+type ResubmitAttempt struct {
+	Attempt  int        `json:"attempt"`
+	TaskId   string     `json:"taskId"`
+	Status   TaskStatus `json:"status"`
+	Code     int        `json:"code"`
+	Msg      string     `json:"msg"`
+	Category string     `json:"category"`
+	Action   string     `json:"action"` // 本次结果之后采取的处理方式
+	Err      error      `json:"-"`
+}
+
+// ResubmitRes 作业的完整提交链
+type ResubmitRes struct {
+	JobId     string                     `json:"jobId"`
+	Attempts  []*ResubmitAttempt         `json:"attempts"`
+	Final     *GetTaskStatusAndResultRes `json:"final"`
+	Succeeded bool                       `json:"succeeded"`
+}
+
+// Resubmitter 按失败分类策略自动重新提交失败的任务
+type Resubmitter struct {
+	cfg *ResubmitterConfig
+}
+
+func NewResubmitter(in *ResubmitterConfig) *Resubmitter {
+	if in.Actions == nil {
+		in.Actions = DefaultResubmitActions()
+	}
+	if in.CodeActions == nil {
+		in.CodeActions = DefaultResubmitCodeActions()
+	}
+	if in.MaxAttempts <= 0 {
+		in.MaxAttempts = 3
+	}
+	if in.Delay <= 0 {
+		in.Delay = 30 * time.Second
+	}
+	if len(in.SeedFieldNames) == 0 {
+		in.SeedFieldNames = []string{"seed", "noise_seed"}
+	}
+	return &Resubmitter{
+		cfg: in,
+	}
+}
+
+// Decide 根据任务结果决定处理方式
+func (r *Resubmitter) Decide(res *GetTaskStatusAndResultRes) (category string, action string) {
+	classifier := r.cfg.Classifier
+	if classifier == nil {
+		classifier = DefaultFailureClassifier()
+	}
+	classification := classifier.Classify(res.FailedReason)
+	category = classification.Category
+	if action, ok := r.cfg.CodeActions[res.Code]; ok {
+		return category, action
+	}
+	// 规则明确标记不可重试时不重新提交, 未命中规则(unknown)按 Actions 处理
+	if classification.Rule != "" && !classification.Retryable && !r.cfg.RetryNonRetryable {
+		return category, ResubmitActionGiveUp
+	}
+	if action, ok := r.cfg.Actions[category]; ok {
+		return category, action
+	}
+	return category, ResubmitActionGiveUp
+}
+
+// Resubmit 对已失败的任务按策略重新提交并等待结果, 直到成功、放弃或达到最大提交次数;
+// jobId 为原始作业 id, 为空时使用 failedTaskId
+func (r *Resubmitter) Resubmit(ctx context.Context, jobId string, failedTaskId string, req *CreateTaskReq, failed *GetTaskStatusAndResultRes) (res *ResubmitRes, err error) {
+	return r.resubmit(ctx, jobId, failedTaskId, req, failed, 0)
+}
+
+// resubmit 同 Resubmit, firstAttempt 为失败任务的提交次数, 为 0 时从 TaskStore 读取
+func (r *Resubmitter) resubmit(ctx context.Context, jobId string, failedTaskId string, req *CreateTaskReq, failed *GetTaskStatusAndResultRes, firstAttempt int) (res *ResubmitRes, err error) {
+	if req == nil || failed == nil {
+		return nil, gerror.New("create task request and failed result cannot be nil")
+	}
+	if jobId == "" {
+		jobId = failedTaskId
+	}
+	res = &ResubmitRes{
+		JobId: jobId,
+		Final: failed,
+	}
+	attempt := &ResubmitAttempt{
+		Attempt: 1,
+		TaskId:  failedTaskId,
+		Status:  failed.Status,
+		Code:    failed.Code,
+		Msg:     failed.Msg,
+	}
+	// 沿用已记录的提交次数
+	if firstAttempt > 0 {
+		attempt.Attempt = firstAttempt
+	} else if r.cfg.TaskStore != nil {
+		if records, err := r.cfg.TaskStore.ListJob(ctx, jobId); err == nil && len(records) > 0 {
+			attempt.Attempt = records[len(records)-1].Attempt
+		}
+	}
+	attempt.Category, attempt.Action = r.Decide(failed)
+	res.Attempts = append(res.Attempts, attempt)
+	r.save(ctx, jobId, attempt, req, failed)
+
+	for attempt.Action != ResubmitActionGiveUp && attempt.Attempt < r.cfg.MaxAttempts {
+		nextReq := req
+		switch attempt.Action {
+		case ResubmitActionDelay:
+			if err = sleepContext(ctx, r.cfg.Delay); err != nil {
+				return res, err
+			}
+		case ResubmitActionNewSeed:
+			nextReq = r.withNewSeed(req)
+		}
+		attempt = &ResubmitAttempt{Attempt: attempt.Attempt + 1}
+		res.Attempts = append(res.Attempts, attempt)
+
+		taskId, wait, err := r.submit(ctx, nextReq)
+		if err != nil {
+			attempt.Err = err
+			attempt.Status = TaskStatusFailed
+			attempt.Code, _ = ApiErrorCode(err)
+			attempt.Msg = err.Error()
+			attempt.Action = ResubmitActionGiveUp
+			if action, ok := r.cfg.CodeActions[attempt.Code]; ok {
+				attempt.Action = action
+			}
+			continue
+		}
+		attempt.TaskId = taskId
+		result, err := wait()
+		if err != nil {
+			attempt.Err = err
+			return res, err
+		}
+		res.Final = result
+		attempt.Status = result.Status
+		attempt.Code = result.Code
+		attempt.Msg = result.Msg
+		if result.Status == TaskStatusSuccess {
+			res.Succeeded = true
+			r.save(ctx, jobId, attempt, nextReq, result)
+			return res, nil
+		}
+		attempt.Category, attempt.Action = r.Decide(result)
+		r.save(ctx, jobId, attempt, nextReq, result)
+	}
+	return res, nil
+}
+
+// submit 创建任务, 返回等待任务结束的函数; 设置 HandleOption 时通过 TaskHandle 等待
+func (r *Resubmitter) submit(ctx context.Context, req *CreateTaskReq) (taskId string, wait func() (*GetTaskStatusAndResultRes, error), err error) {
+	if r.cfg.HandleOption != nil {
+		handle, err := r.cfg.Client.StartTask(ctx, req, r.cfg.HandleOption)
+		if err != nil {
+			return "", nil, err
+		}
+		return handle.TaskId, handle.Wait, nil
+	}
+	createRes, err := r.cfg.Client.CreateTask(ctx, req)
+	if err != nil {
+		return "", nil, err
+	}
+	return createRes.TaskId, func() (*GetTaskStatusAndResultRes, error) {
+		return r.cfg.Client.WaitTask(ctx, createRes.TaskId, r.cfg.PollInterval)
+	}, nil
+}
+
+// startTask 创建任务并绑定 ctx, 创建失败且错误码的处理方式为 retry / delay 时在 MaxAttempts 内重试, 返回已使用的提交次数
+func (r *Resubmitter) startTask(ctx context.Context, req *CreateTaskReq, opt *TaskHandleOption) (handle *TaskHandle, attempts int, err error) {
+	for attempts = 1; ; attempts++ {
+		handle, err = r.cfg.Client.StartTask(ctx, req, opt)
+		if err == nil {
+			return handle, attempts, nil
+		}
+		code, _ := ApiErrorCode(err)
+		action := r.cfg.CodeActions[code]
+		if attempts >= r.cfg.MaxAttempts || action != ResubmitActionRetry && action != ResubmitActionDelay {
+			return nil, attempts, err
+		}
+		if action == ResubmitActionDelay {
+			if sleepErr := sleepContext(ctx, r.cfg.Delay); sleepErr != nil {
+				return nil, attempts, err
+			}
+		}
+	}
+}
+
+// withNewSeed 复制请求并为种子字段生成新的随机值
+func (r *Resubmitter) withNewSeed(req *CreateTaskReq) *CreateTaskReq {
+	next := *req
+	next.NodeInfoList = make([]*NodeInfo, 0, len(req.NodeInfoList))
+	for _, v := range req.NodeInfoList {
+		nodeInfo := *v
+		for _, name := range r.cfg.SeedFieldNames {
+			if nodeInfo.FieldName == name {
+				nodeInfo.FieldValue = gconv.String(rand.Int63n(1 << 48))
+				break
+			}
+		}
+		next.NodeInfoList = append(next.NodeInfoList, &nodeInfo)
+	}
+	return &next
+}
+
+func (r *Resubmitter) save(ctx context.Context, jobId string, attempt *ResubmitAttempt, req *CreateTaskReq, result *GetTaskStatusAndResultRes) {
+	if r.cfg.TaskStore == nil || attempt.TaskId == "" {
+		return
+	}
+	record := &TaskRecord{
+		JobId:           jobId,
+		TaskId:          attempt.TaskId,
+		Attempt:         attempt.Attempt,
+		WorkflowId:      req.WorkflowId,
+		Request:         req,
+		Status:          attempt.Status,
+		Code:            attempt.Code,
+		Msg:             attempt.Msg,
+		FailureCategory: attempt.Category,
+		FinishedAt:      time.Now(),
+	}
+	if old, ok, err := r.cfg.TaskStore.Get(ctx, attempt.TaskId); err == nil && ok {
+		record.CreatedAt = old.CreatedAt
+		record.Tags = old.Tags
+	}
+	if result != nil {
+		record.Cost = TaskCostFromResult(result.SuccessItems)
+	}
+	// 记录失败不影响重新提交
+	_ = r.cfg.TaskStore.Save(ctx, record)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,174 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResubmitterDecide(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *ResubmitterConfig
+		res      *GetTaskStatusAndResultRes
+		category string
+		action   string
+	}{
+		{
+			name:     "vram alert retries",
+			cfg:      &ResubmitterConfig{},
+			res:      &GetTaskStatusAndResultRes{Code: 805, FailedReason: &FailedReason{ExceptionMessage: "显存告警"}},
+			category: FailureCategoryVramAlert,
+			action:   ResubmitActionRetry,
+		},
+		{
+			name:     "content moderation gives up",
+			cfg:      &ResubmitterConfig{},
+			res:      &GetTaskStatusAndResultRes{Code: 805, FailedReason: &FailedReason{ExceptionMessage: "nsfw detected"}},
+			category: FailureCategoryContentModeration,
+			action:   ResubmitActionGiveUp,
+		},
+		{
+			name: "non retryable ignores configured action",
+			cfg: &ResubmitterConfig{Actions: map[string]string{
+				FailureCategoryContentModeration: ResubmitActionNewSeed,
+			}},
+			res:      &GetTaskStatusAndResultRes{Code: 805, FailedReason: &FailedReason{ExceptionMessage: "nsfw detected"}},
+			category: FailureCategoryContentModeration,
+			action:   ResubmitActionGiveUp,
+		},
+		{
+			name: "explicit override",
+			cfg: &ResubmitterConfig{
+				Actions:           map[string]string{FailureCategoryContentModeration: ResubmitActionNewSeed},
+				RetryNonRetryable: true,
+			},
+			res:      &GetTaskStatusAndResultRes{Code: 805, FailedReason: &FailedReason{ExceptionMessage: "nsfw detected"}},
+			category: FailureCategoryContentModeration,
+			action:   ResubmitActionNewSeed,
+		},
+		{
+			name: "unknown follows actions",
+			cfg: &ResubmitterConfig{Actions: map[string]string{
+				FailureCategoryUnknown: ResubmitActionRetry,
+			}},
+			res:      &GetTaskStatusAndResultRes{Code: 805, FailedReason: &FailedReason{ExceptionMessage: "boom"}},
+			category: FailureCategoryUnknown,
+			action:   ResubmitActionRetry,
+		},
+		{
+			name:     "code action first",
+			cfg:      &ResubmitterConfig{},
+			res:      &GetTaskStatusAndResultRes{Code: 421, FailedReason: &FailedReason{ExceptionMessage: "nsfw detected"}},
+			category: FailureCategoryContentModeration,
+			action:   ResubmitActionDelay,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			category, action := NewResubmitter(tt.cfg).Decide(tt.res)
+			if category != tt.category || action != tt.action {
+				t.Fatalf("Decide() = %s, %s, want %s, %s", category, action, tt.category, tt.action)
+			}
+		})
+	}
+}
+
+func TestResubmitterChain(t *testing.T) {
+	vramAlert := &FailedReason{ExceptionMessage: "显存告警"}
+	tests := []struct {
+		name         string
+		jobId        string
+		firstAttempt int  // 失败任务已记录的提交次数, 0 表示没有记录
+		succeedAfter bool // 首个任务失败后新任务成功
+		maxAttempts  int
+		wantJobId    string
+		want         string // taskId:attempt:status, 按提交顺序
+		wantSuccess  bool
+	}{
+		{name: "exhausts max attempts", maxAttempts: 3, wantJobId: "task-1", want: "task-1:1:FAILED,task-2:2:FAILED,task-3:3:FAILED"},
+		{name: "succeeds on resubmit", jobId: "job-1", succeedAfter: true, maxAttempts: 3, wantJobId: "job-1", want: "task-1:1:FAILED,task-2:2:SUCCESS", wantSuccess: true},
+		{name: "continues recorded attempts", jobId: "job-1", firstAttempt: 2, maxAttempts: 3, wantJobId: "job-1", want: "task-1:2:FAILED,task-2:3:FAILED"},
+		{name: "recorded attempts reach max", firstAttempt: 3, maxAttempts: 3, wantJobId: "task-1", want: "task-1:3:FAILED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeRunningHub()
+			fake.Final, fake.Reason = TaskStatusFailed, vramAlert
+			client := fake.client(t)
+			ctx := context.Background()
+			req := testCreateTaskReq()
+			createRes, err := client.CreateTask(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			failed, err := client.WaitTask(ctx, createRes.TaskId, 10*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			store := NewTaskStore(NewMemoryStore())
+			if tt.firstAttempt > 0 {
+				err = store.Save(ctx, &TaskRecord{JobId: tt.wantJobId, TaskId: createRes.TaskId, Attempt: tt.firstAttempt, Status: TaskStatusRunning, Tags: []string{"first"}})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.succeedAfter {
+				fake.mu.Lock()
+				fake.Final = TaskStatusSuccess
+				fake.mu.Unlock()
+			}
+			r := NewResubmitter(&ResubmitterConfig{
+				Client:       client,
+				TaskStore:    store,
+				MaxAttempts:  tt.maxAttempts,
+				Delay:        time.Millisecond,
+				PollInterval: 10 * time.Millisecond,
+			})
+			res, err := r.Resubmit(ctx, tt.jobId, createRes.TaskId, req, failed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.JobId != tt.wantJobId || res.Succeeded != tt.wantSuccess || res.Final.Status != res.Attempts[len(res.Attempts)-1].Status {
+				t.Fatalf("Resubmit() = %+v", res)
+			}
+			var got []string
+			for _, v := range res.Attempts {
+				got = append(got, fmt.Sprintf("%s:%d:%s", v.TaskId, v.Attempt, v.Status))
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Fatalf("attempts = %v, want %s", got, tt.want)
+			}
+			// 失败的提交都记录了分类与处理方式, 达到上限时停止
+			for _, v := range res.Attempts {
+				if v.Status == TaskStatusFailed && (v.Category != FailureCategoryVramAlert || v.Action != ResubmitActionRetry) {
+					t.Fatalf("attempt %d = %+v", v.Attempt, v)
+				}
+			}
+			if n := fake.createCount(); n != len(res.Attempts) {
+				t.Fatalf("creates = %d, want %d", n, len(res.Attempts))
+			}
+
+			// TaskStore 中的作业记录按提交顺序排列, 与提交链一致
+			records, err := store.ListJob(ctx, tt.wantJobId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = got[:0]
+			for _, v := range records {
+				if v.JobId != tt.wantJobId || v.Request == nil || v.Request.ApiKey != "" {
+					t.Fatalf("record = %+v", v)
+				}
+				got = append(got, fmt.Sprintf("%s:%d:%s", v.TaskId, v.Attempt, v.Status))
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Fatalf("ListJob() = %v, want %s", got, tt.want)
+			}
+			if tt.firstAttempt > 0 && (len(records[0].Tags) != 1 || records[0].Tags[0] != "first") {
+				t.Fatalf("first record tags = %v", records[0].Tags)
+			}
+		})
+	}
+}
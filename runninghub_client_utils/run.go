@@ -0,0 +1,341 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"fmt"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"time"
+)
+
+// RunInput 单个节点输入, Source 不为空时按 NodeType 上传或编码后填入, 否则使用字面值 Value
+type RunInput struct {
+	NodeId    string
+	FieldName string
+	Value     string
+	Source    *FileSource
+	NodeType  string // NodeTypeFile / NodeTypeUrl / NodeTypeBase64, 默认 NodeTypeFile
+}
+
+type RunOptions struct {
+	Timeout      time.Duration // 整体超时, 0 不限制; 超时后按 GracePeriod 取消远程任务, 包括重新提交的任务
+	GracePeriod  time.Duration // 同 TaskHandleOption.GracePeriod
+	PollInterval time.Duration // 轮询间隔, 默认 5s
+	// WebhookUrl 不为空时随任务提交; 设置 WebhookWait 时由其等待回调结果, 否则仍轮询; WebhookWait 返回错误时改为轮询
+	WebhookUrl  string
+	WebhookWait func(ctx context.Context, taskId string) (*GetTaskStatusAndResultRes, error)
+	Bind        *BindPictureInputsOption // 上传或编码 Source 的参数
+	DownloadDir string                   // 不为空时下载输出文件到该目录
+	Download    *DownloadTaskOutputsOption
+	// 以下同 CreateTaskReq 中的字段
+	InstanceType     string
+	UsePersonalQueue bool
+	AddMetadata      bool
+	Workflow         string // 需为 API 格式的 JSON 对象, Run 按 SetWorkflow 的规则校验
+
+	TaskStore   *TaskStore         // 不为空时记录任务及重新提交的结果
+	MaxAttempts int                // 包括首次提交在内的最大提交次数, 大于 1 时创建失败或任务失败后按 Resubmitter 策略重新提交
+	Resubmitter *ResubmitterConfig // 重新提交策略, Client, MaxAttempts, PollInterval, HandleOption 由 Run 填充
+}
+
+type RunRequest struct {
+	WorkflowId string
+	Inputs     []*RunInput
+	Options    *RunOptions
+	Tags       []string // 写入 TaskStore 记录的标签
+}
+
+// RunOutput 单个输出文件
+type RunOutput struct {
+	Index     int    `json:"index"`
+	NodeId    string `json:"nodeId"`
+	FileUrl   string `json:"fileUrl"`
+	FileType  string `json:"fileType"`
+	LocalPath string `json:"localPath"` // 仅设置 DownloadDir 时返回
+	Size      int64  `json:"size"`
+}
+
+// RunTimings 各阶段耗时, Queue 与 Execute 仅轮询时可区分
+type RunTimings struct {
+	Upload   time.Duration `json:"upload"`
+	Queue    time.Duration `json:"queue"`
+	Execute  time.Duration `json:"execute"`
+	Download time.Duration `json:"download"`
+	Total    time.Duration `json:"total"`
+	TaskCost string        `json:"taskCost"` // 平台返回的 taskCostTime
+}
+
+type RunResult struct {
+	TaskId   string                     `json:"taskId"`
+	Attempts []*ResubmitAttempt         `json:"attempts"` // 仅发生重新提交时返回
+	Outputs  []*RunOutput               `json:"outputs"`
+	Cost     Cost                       `json:"cost"`
+	Timings  RunTimings                 `json:"timings"`
+	Raw      *GetTaskStatusAndResultRes `json:"raw"`
+}
+
+// RunError 任务失败时 Run 返回的错误
+type RunError struct {
+	TaskId string
+	Status TaskStatus
+	Info   *ErrorInfo
+}
+
+func (e *RunError) Error() string {
+	return fmt.Sprintf("run task(%s) fail, status: %s, code: %d, msg: %s", e.TaskId, e.Status, e.Info.Code, e.Info.Msg)
+}
+
+// Run 依次上传输入、创建任务、等待结果并下载输出; 任务失败时返回 *RunError 及已有的 RunResult
+func (c *RunningHubClient) Run(ctx context.Context, in RunRequest) (res *RunResult, err error) {
+	opt := in.Options
+	if opt == nil {
+		opt = &RunOptions{}
+	}
+	if opt.PollInterval <= 0 {
+		opt.PollInterval = 5 * time.Second
+	}
+	if opt.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opt.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	res = &RunResult{}
+	defer func() {
+		res.Timings.Total = time.Since(start)
+	}()
+
+	// 自定义工作流按 SetWorkflow 的规则校验, 不合法时不上传输入
+	if opt.Workflow != "" {
+		if err = (&CreateTaskReq{}).SetWorkflow(opt.Workflow); err != nil {
+			return res, err
+		}
+	}
+	nodeInfoList, err := c.buildRunNodeInfoList(ctx, in.Inputs, opt)
+	if err != nil {
+		return res, err
+	}
+	res.Timings.Upload = time.Since(start)
+
+	req := &CreateTaskReq{
+		WorkflowId:       in.WorkflowId,
+		NodeInfoList:     nodeInfoList,
+		WebhookUrl:       opt.WebhookUrl,
+		InstanceType:     opt.InstanceType,
+		UsePersonalQueue: opt.UsePersonalQueue,
+		AddMetadata:      opt.AddMetadata,
+		Workflow:         opt.Workflow,
+	}
+	handleOpt := &TaskHandleOption{GracePeriod: opt.GracePeriod, PollInterval: opt.PollInterval}
+	var resubmitter *Resubmitter
+	if opt.MaxAttempts > 1 {
+		cfg := &ResubmitterConfig{}
+		if opt.Resubmitter != nil {
+			*cfg = *opt.Resubmitter
+		}
+		cfg.Client, cfg.MaxAttempts, cfg.PollInterval = c, opt.MaxAttempts, opt.PollInterval
+		// 重新提交的任务同样在 ctx 取消或超时后取消远程任务
+		cfg.HandleOption = handleOpt
+		if cfg.TaskStore == nil {
+			cfg.TaskStore = opt.TaskStore
+		}
+		resubmitter = NewResubmitter(cfg)
+	}
+	// 首个任务由 waitRun 轮询以区分排队与执行耗时
+	firstOpt := *handleOpt
+	firstOpt.passive = true
+	var (
+		handle   *TaskHandle
+		attempts = 1
+	)
+	if resubmitter != nil {
+		// 并发已满等错误码按策略重试创建
+		handle, attempts, err = resubmitter.startTask(ctx, req, &firstOpt)
+	} else {
+		handle, err = c.StartTask(ctx, req, &firstOpt)
+	}
+	if err != nil {
+		return res, err
+	}
+	res.TaskId = handle.TaskId
+	createdAt := time.Now()
+	final, err := c.waitRun(ctx, handle, opt, &res.Timings)
+	if err != nil {
+		return res, err
+	}
+	if opt.TaskStore != nil {
+		record := &TaskRecord{
+			TaskId:     res.TaskId,
+			WorkflowId: req.WorkflowId,
+			Request:    req,
+			Status:     final.Status,
+			Code:       final.Code,
+			Msg:        final.Msg,
+			Cost:       TaskCostFromResult(final.SuccessItems),
+			Tags:       in.Tags,
+			CreatedAt:  createdAt,
+			FinishedAt: time.Now(),
+		}
+		// 结果中的 FailedReason 总是非空, 只对失败任务分类
+		if final.Status == TaskStatusFailed {
+			record.FailureCategory = DefaultFailureClassifier().Classify(final.FailedReason).Category
+		}
+		// 记录失败不影响任务结果
+		_ = opt.TaskStore.Save(ctx, record)
+	}
+
+	if final.Status != TaskStatusSuccess && resubmitter != nil {
+		resubmitRes, err := resubmitter.resubmit(ctx, res.TaskId, res.TaskId, req, final, attempts)
+		if resubmitRes != nil {
+			res.Attempts = resubmitRes.Attempts
+			final = resubmitRes.Final
+			if last := resubmitRes.Attempts[len(resubmitRes.Attempts)-1]; last.TaskId != "" {
+				res.TaskId = last.TaskId
+			}
+		}
+		if resubmitter.cfg.TaskStore != nil {
+			c.tagRunAttempts(ctx, resubmitter.cfg.TaskStore, res.Attempts, in.Tags)
+		}
+		if err != nil {
+			return res, err
+		}
+	}
+	res.Raw = final
+	res.Cost = TaskCostFromResult(final.SuccessItems)
+	if final.Status != TaskStatusSuccess {
+		return res, &RunError{
+			TaskId: res.TaskId,
+			Status: final.Status,
+			Info:   c.GetErrorInfo(final.Code, final.Msg, final.FailedReason),
+		}
+	}
+
+	for i, v := range final.SuccessItems {
+		if v == nil {
+			continue
+		}
+		if res.Timings.TaskCost == "" {
+			res.Timings.TaskCost = v.TaskCostTime
+		}
+		res.Outputs = append(res.Outputs, &RunOutput{
+			Index:    i,
+			NodeId:   v.NodeId,
+			FileUrl:  v.FileUrl,
+			FileType: v.FileType,
+		})
+	}
+	if opt.DownloadDir != "" {
+		downloadStart := time.Now()
+		downloadOpt := &DownloadTaskOutputsOption{}
+		if opt.Download != nil {
+			*downloadOpt = *opt.Download
+		}
+		downloadOpt.TaskId = res.TaskId
+		downloadRes, err := DownloadTaskOutputs(ctx, final, opt.DownloadDir, downloadOpt)
+		res.Timings.Download = time.Since(downloadStart)
+		if downloadRes != nil {
+			for _, output := range res.Outputs {
+				if item := downloadRes.Items[output.Index]; item != nil && item.Err == nil {
+					output.LocalPath = item.LocalPath
+					output.Size = item.Size
+				}
+			}
+		}
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+func (c *RunningHubClient) buildRunNodeInfoList(ctx context.Context, inputs []*RunInput, opt *RunOptions) ([]*NodeInfo, error) {
+	bindOpt := opt.Bind
+	if bindOpt == nil {
+		bindOpt = &BindPictureInputsOption{}
+	}
+	res := make([]*NodeInfo, 0, len(inputs))
+	for _, v := range inputs {
+		if v == nil {
+			continue
+		}
+		if v.Source == nil {
+			res = append(res, &NodeInfo{NodeId: v.NodeId, FieldName: v.FieldName, FieldValue: v.Value})
+			continue
+		}
+		node := &WorkflowNodeInfo{
+			NodeType:  v.NodeType,
+			NodeId:    v.NodeId,
+			FieldName: v.FieldName,
+		}
+		if node.NodeType == "" {
+			node.NodeType = NodeTypeFile
+		}
+		nodeInfo, err := c.bindPictureInput(ctx, node, v.Source, bindOpt)
+		if err != nil {
+			return nil, gerror.Wrapf(err, "prepare input node(%s.%s) fail", v.NodeId, v.FieldName)
+		}
+		res = append(res, nodeInfo)
+	}
+	return res, nil
+}
+
+// tagRunAttempts 为重新提交产生的任务记录补充标签
+func (c *RunningHubClient) tagRunAttempts(ctx context.Context, store *TaskStore, attempts []*ResubmitAttempt, tags []string) {
+	if len(tags) == 0 {
+		return
+	}
+	for _, v := range attempts {
+		if v.TaskId == "" {
+			continue
+		}
+		record, ok, err := store.Get(ctx, v.TaskId)
+		if err != nil || !ok || len(record.Tags) > 0 {
+			continue
+		}
+		record.Tags = tags
+		_ = store.Save(ctx, record)
+	}
+}
+
+// waitRun 等待任务结束, 轮询时记录排队与执行耗时
+// 等待回调失败时任务可能仍在执行, 改为轮询直到任务结束或 ctx 结束, 保证句柄最终结束或取消远程任务
+func (c *RunningHubClient) waitRun(ctx context.Context, handle *TaskHandle, opt *RunOptions, timings *RunTimings) (res *GetTaskStatusAndResultRes, err error) {
+	submittedAt := time.Now()
+	if opt.WebhookUrl != "" && opt.WebhookWait != nil {
+		res, err = opt.WebhookWait(ctx, handle.TaskId)
+		if err == nil && res != nil {
+			timings.Execute = time.Since(submittedAt)
+			handle.finish(res)
+			return res, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	var runningAt time.Time
+	ticker := time.NewTicker(opt.PollInterval)
+	defer ticker.Stop()
+	for {
+		res, err = c.GetTaskStatusAndResult(ctx, handle.TaskId)
+		// 查询接口偶发错误或状态回退时继续轮询
+		if err == nil && handle.observe(res.Status) == nil {
+			if res.Status == TaskStatusRunning && runningAt.IsZero() {
+				runningAt = time.Now()
+				timings.Queue = runningAt.Sub(submittedAt)
+			}
+			if res.Status.IsTerminal() {
+				if runningAt.IsZero() {
+					timings.Execute = time.Since(submittedAt)
+				} else {
+					timings.Execute = time.Since(runningAt)
+				}
+				handle.finish(res)
+				return res, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return res, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
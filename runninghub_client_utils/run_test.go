@@ -0,0 +1,134 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func testRunRequest(opt *RunOptions) RunRequest {
+	return RunRequest{
+		WorkflowId: "wf",
+		Inputs:     []*RunInput{{NodeId: "6", FieldName: "text", Value: "a cat"}},
+		Options:    opt,
+		Tags:       []string{"test"},
+	}
+}
+
+func TestRun(t *testing.T) {
+	vramAlert := &FailedReason{ExceptionMessage: "显存告警"}
+	tests := []struct {
+		name           string
+		createFailures int
+		createCode     int
+		finishAts      []int
+		final          TaskStatus
+		reason         *FailedReason
+		maxAttempts    int
+		wantErr        bool
+		wantCreates    int
+		wantAttempts   int
+	}{
+		{name: "success", wantCreates: 1},
+		{name: "concurrency limit on first create", createFailures: 2, createCode: 421, maxAttempts: 3, wantCreates: 3},
+		{name: "concurrency limit without resubmit", createFailures: 1, createCode: 421, wantErr: true, wantCreates: 1},
+		{name: "concurrency limit exhausts attempts", createFailures: 3, createCode: 421, maxAttempts: 3, wantErr: true, wantCreates: 3},
+		{name: "failed task is resubmitted", final: TaskStatusFailed, reason: vramAlert, maxAttempts: 2, wantErr: true, wantCreates: 2, wantAttempts: 2},
+		{name: "moderated task is not resubmitted", final: TaskStatusFailed, reason: &FailedReason{ExceptionMessage: "nsfw"}, maxAttempts: 3, wantErr: true, wantCreates: 1, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeRunningHub()
+			fake.CreateFailures, fake.CreateCode = tt.createFailures, tt.createCode
+			fake.FinishAts, fake.Final, fake.Reason = tt.finishAts, tt.final, tt.reason
+			fake.Money = "0.5"
+			client := fake.client(t)
+			store := NewTaskStore(NewMemoryStore())
+			res, err := client.Run(context.Background(), testRunRequest(&RunOptions{
+				PollInterval: 10 * time.Millisecond,
+				MaxAttempts:  tt.maxAttempts,
+				TaskStore:    store,
+				Resubmitter:  &ResubmitterConfig{Delay: time.Millisecond},
+			}))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if n := fake.createCount(); n != tt.wantCreates {
+				t.Fatalf("creates = %d, want %d", n, tt.wantCreates)
+			}
+			if len(res.Attempts) != tt.wantAttempts {
+				t.Fatalf("attempts = %d, want %d", len(res.Attempts), tt.wantAttempts)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(res.Outputs) != 1 || res.Cost.Money != 0.5 || res.Raw.Status != TaskStatusSuccess {
+				t.Fatalf("Run() = %+v", res)
+			}
+			record, ok, err := store.Get(context.Background(), res.TaskId)
+			if err != nil || !ok {
+				t.Fatalf("record not saved: %v", err)
+			}
+			if record.Status != TaskStatusSuccess || record.FailureCategory != "" || len(record.Tags) != 1 {
+				t.Fatalf("record = %+v", record)
+			}
+			if len(client.InflightTasks()) != 0 {
+				t.Fatalf("InflightTasks() = %v", client.InflightTasks())
+			}
+		})
+	}
+}
+
+func TestRunTimeoutCancelsResubmittedTask(t *testing.T) {
+	fake := newFakeRunningHub()
+	// 首个任务很快失败, 重新提交的任务不会自行结束
+	fake.FinishAts = []int{1, -1}
+	fake.Final, fake.Reason = TaskStatusFailed, &FailedReason{ExceptionMessage: "显存告警"}
+	client := fake.client(t)
+	_, err := client.Run(context.Background(), testRunRequest(&RunOptions{
+		Timeout:      300 * time.Millisecond,
+		GracePeriod:  20 * time.Millisecond,
+		PollInterval: 10 * time.Millisecond,
+		MaxAttempts:  3,
+	}))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run() err = %v, want context.DeadlineExceeded", err)
+	}
+	taskIds := fake.taskIds()
+	if len(taskIds) != 2 {
+		t.Fatalf("tasks = %v, want 2", taskIds)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !fake.task(taskIds[1]).cancelled {
+		if time.Now().After(deadline) {
+			t.Fatal("resubmitted task not cancelled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunWebhookWaitErrorFallsBackToPolling(t *testing.T) {
+	fake := newFakeRunningHub()
+	fake.FinishAt = 2
+	client := fake.client(t)
+	waits := 0
+	res, err := client.Run(context.Background(), testRunRequest(&RunOptions{
+		PollInterval: 10 * time.Millisecond,
+		WebhookUrl:   "https://example.com/hook",
+		WebhookWait: func(ctx context.Context, taskId string) (*GetTaskStatusAndResultRes, error) {
+			waits++
+			return nil, errors.New("webhook receiver stopped")
+		},
+	}))
+	if err != nil || res.Raw.Status != TaskStatusSuccess {
+		t.Fatalf("Run() = %+v, %v", res, err)
+	}
+	if waits != 1 || fake.task(res.TaskId).polls < 2 {
+		t.Fatalf("waits = %d, polls = %d", waits, fake.task(res.TaskId).polls)
+	}
+	// 句柄已结束, 不再被跟踪
+	if len(client.InflightTasks()) != 0 {
+		t.Fatalf("InflightTasks() = %v", client.InflightTasks())
+	}
+}
@@ -12,12 +12,11 @@ import (
 	"github.com/gogf/gf/v2/net/gclient"
 	"github.com/gogf/gf/v2/os/gfile"
 	"github.com/gogf/gf/v2/util/gconv"
-	"io"
-	"log"
-	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
@@ -31,20 +30,18 @@ const (
 	getWorkflowJSON  = "/api/openapi/getJsonApiFormat"
 	uploadResource   = "/task/openapi/upload"
 	getLoraUploadUrl = "/api/openapi/getLoraUploadUrl"
-)
-
-const (
-	TaskStatusSuccess = "SUCCESS"
-	TaskStatusFailed  = "FAILED"
-	TaskStatusRunning = "RUNNING"
-	TaskStatusQueued  = "QUEUED"
+	getWebAppInfo    = "/api/webapp/apiCallDemo"
+	createWebAppTask = "/task/openapi/ai-app/run"
 )
 
 type RunningHubClient struct {
-	url        string
-	ApiKey     string `json:"api_key"`
-	httpClient *gclient.Client
-	Timeout    time.Duration
+	url         string
+	ApiKey      string `json:"api_key"`
+	httpClient  *gclient.Client
+	Timeout     time.Duration
+	uploadCache *UploadCache
+	Lang        string
+	inflight    *inflightTasks
 }
 
 func NewClient(in *RunningHubClientConfig) *RunningHubClient {
@@ -60,9 +57,12 @@ func NewClient(in *RunningHubClientConfig) *RunningHubClient {
 	}
 	url := fmt.Sprintf("%s://%s", protocol, in.Host)
 	return &RunningHubClient{
-		ApiKey:  in.ApiKey,
-		Timeout: in.Timeout,
-		url:     url,
+		ApiKey:      in.ApiKey,
+		Timeout:     in.Timeout,
+		url:         url,
+		uploadCache: in.UploadCache,
+		Lang:        in.Lang,
+		inflight:    newInflightTasks(),
 		httpClient: g.Client().
 			SetTimeout(in.Timeout*time.Second).
 			SetHeader("Content-Type", "application/json").
@@ -100,12 +100,9 @@ func (c *RunningHubClient) doPostWithHeader(ctx context.Context, url string, hea
 	return response, nil
 }
 
-func (c *RunningHubClient) doPostContentWithHeader(ctx context.Context, url string, header map[string]string, payload *bytes.Buffer) (res *RunningHubResponse, err error) {
+func (c *RunningHubClient) doPost(ctx context.Context, url string, reqBody []byte) (res *RunningHubResponse, err error) {
 	httpClient := c.httpClient.Clone()
-	for k, v := range header {
-		httpClient = httpClient.SetHeader(k, v)
-	}
-	resp, err := httpClient.Post(ctx, url, payload)
+	resp, err := httpClient.Post(ctx, url, reqBody)
 	if err != nil {
 		return nil, err
 	}
@@ -130,30 +127,17 @@ func (c *RunningHubClient) doPostContentWithHeader(ctx context.Context, url stri
 	return response, nil
 }
 
-func (c *RunningHubClient) doPutWithHeader(ctx context.Context, url string, header map[string]string, payload *os.File) (err error) {
-	httpClient := g.Client().
-		SetTimeout(0).
-		SetHeader("Authorization", "Bearer "+c.ApiKey)
-	for k, v := range header {
-		httpClient = httpClient.SetHeader(k, v)
-	}
-	resp, err := httpClient.Put(ctx, url, payload)
-	if err != nil {
-		return err
+// doGet 发送 GET 请求, params 编码为查询参数; 客户端默认的 JSON Content-Type 会使 gclient 把参数写入请求体
+func (c *RunningHubClient) doGet(ctx context.Context, reqUrl string, params g.Map) (res *RunningHubResponse, err error) {
+	query := url.Values{}
+	for k, v := range params {
+		query.Set(k, gconv.String(v))
 	}
-	defer resp.Close()
-
-	// 检查响应状态码
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return gerror.Newf("unexpected status code, respCode: %d, respBody: %v", resp.StatusCode, string(body))
+	if len(query) > 0 {
+		reqUrl += "?" + query.Encode()
 	}
-	return nil
-}
-
-func (c *RunningHubClient) doPost(ctx context.Context, url string, reqBody []byte) (res *RunningHubResponse, err error) {
 	httpClient := c.httpClient.Clone()
-	resp, err := httpClient.Post(ctx, url, reqBody)
+	resp, err := httpClient.Get(ctx, reqUrl)
 	if err != nil {
 		return nil, err
 	}
@@ -199,8 +183,11 @@ func (c *RunningHubClient) GetAccountInfo(ctx context.Context) (res *GetAccountR
 
 // CreateTask 创建任务
 func (c *RunningHubClient) CreateTask(ctx context.Context, payloadData *CreateTaskReq) (res *CreateTaskRes, err error) {
-	if payloadData == nil || payloadData.WorkflowId == "" || len(payloadData.NodeInfoList) == 0 {
-		return nil, gerror.New("Running Create Task Request`s Payload data params of WorkflowId and NodeInfoList must be not empty")
+	if payloadData == nil || payloadData.WorkflowId == "" || (len(payloadData.NodeInfoList) == 0 && payloadData.Workflow == "") {
+		return nil, gerror.New("Running Create Task Request`s Payload data params of WorkflowId and NodeInfoList or Workflow must be not empty")
+	}
+	if payloadData.NodeInfoList == nil {
+		payloadData.NodeInfoList = []*NodeInfo{}
 	}
 	url := fmt.Sprintf("%s%s", c.url, createTask)
 	payloadData.ApiKey = c.ApiKey
@@ -211,7 +198,7 @@ func (c *RunningHubClient) CreateTask(ctx context.Context, payloadData *CreateTa
 		return nil, err
 	}
 	if resp.Code != 0 || resp.Data == nil {
-		return nil, gerror.Newf("CreateTask fail, resp: %+v", resp)
+		return nil, &ApiError{Op: "CreateTask", Code: resp.Code, Msg: resp.Msg}
 	}
 	res = &CreateTaskRes{
 		Code: resp.Code,
@@ -248,6 +235,9 @@ func (c *RunningHubClient) GetTaskStatus(ctx context.Context, taskId string) (re
 			return nil, fmt.Errorf("decode success data fail: %w", err)
 		}
 	}
+	if TaskStatusFromResponse(res.Code, res.Data).IsTerminal() {
+		c.untrackTask(taskId)
+	}
 	return res, nil
 }
 
@@ -298,7 +288,7 @@ func (c *RunningHubClient) GetTaskResult(ctx context.Context, taskId string) (re
 // CancelTask 取消任务
 func (c *RunningHubClient) CancelTask(ctx context.Context, taskId string) (err error) {
 	if taskId == "" {
-		log.Fatal("task_id cannot be empty")
+		return gerror.New("task_id cannot be empty")
 	}
 	url := fmt.Sprintf("%s%s", c.url, cancelTask)
 	reqBody, _ := json.Marshal(g.Map{
@@ -310,8 +300,9 @@ func (c *RunningHubClient) CancelTask(ctx context.Context, taskId string) (err e
 		return err
 	}
 	if resp.Code != 0 {
-		return gerror.Newf("CancelTask fail, resp: %+v", resp)
+		return &ApiError{Op: "CancelTask", Code: resp.Code, Msg: resp.Msg}
 	}
+	c.untrackTask(taskId)
 	return nil
 }
 
@@ -332,12 +323,9 @@ func (c *RunningHubClient) GetTaskStatusAndResultWithRetry(ctx context.Context,
 	}
 	res.Code = resp.Code
 	res.Msg = resp.Msg
-	res.Status = strings.ToUpper(resp.Data)
-	if resp.Code != 0 {
-		res.Status = TaskStatusFailed
-	}
-	switch res.Status {
-	case "SUCCESS", "FAILED":
+	// 接口错误不视为任务失败, 状态保持未知
+	res.Status = TaskStatusFromResponse(resp.Code, resp.Data)
+	if res.Status.IsTerminal() {
 		var result *GetTaskResultRes
 		if in.MaxTries <= 0 {
 			in.MaxTries = 5
@@ -436,13 +424,14 @@ func (c *RunningHubClient) DownloadWorkflowJsonData(ctx context.Context, in *Dow
 	return filePath, nil
 }
 
+// UploadResourceWithURl 上传远程文件, 下载内容直接流式写入上传请求, 不落盘
 func (c *RunningHubClient) UploadResourceWithURl(ctx context.Context, url string) (res *UploadResourceRes, err error) {
-	saveFilePath, err := utility.DownloadFromUrl(url, "")
+	body, meta, err := utility.OpenURL(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("download file fail: %w", err)
 	}
-	defer os.Remove(saveFilePath)
-	res, err = c.UploadResource(ctx, saveFilePath)
+	defer body.Close()
+	res, err = c.UploadResourceFromReader(ctx, meta.FileName, body)
 	if err != nil {
 		return nil, fmt.Errorf("upload resource fail: %w", err)
 	}
@@ -453,6 +442,10 @@ func (c *RunningHubClient) UploadResource(ctx context.Context, filePath string)
 	if !gfile.IsFile(filePath) {
 		return nil, errors.New("the filePath does not point to a file")
 	}
+	md5Hex, cached := c.lookupUploadCacheByFile(ctx, uploadCacheV1, filePath)
+	if cached != nil {
+		return &UploadResourceRes{FileName: cached.FileName, FileType: cached.FileType}, nil
+	}
 	url := fmt.Sprintf("%s%s", c.url, uploadResource)
 	body := g.Client().
 		SetTimeout(c.Timeout*time.Second).
@@ -471,6 +464,12 @@ func (c *RunningHubClient) UploadResource(ctx context.Context, filePath string)
 	if err := json.Unmarshal(response.Data, &res); err != nil {
 		return nil, fmt.Errorf("decode success data fail: %w", err)
 	}
+	c.saveUploadCache(ctx, &UploadCacheEntry{
+		Version:  uploadCacheV1,
+		Md5Hex:   md5Hex,
+		FileName: res.FileName,
+		FileType: res.FileType,
+	})
 	return res, nil
 }
 
@@ -491,6 +490,7 @@ func (c *RunningHubClient) ParseWorkflowPictureInputNode(ctx context.Context, wo
 				NodeId:    nodeId,
 				NodeType:  nodeInfo.NodeType,
 				FieldName: nodeInfo.FieldName,
+				Title:     v.Meta["title"],
 			}
 		}
 		for _, cacheV := range gconv.SliceInt(v.Inputs["image"]) {
@@ -506,40 +506,56 @@ func (c *RunningHubClient) ParseWorkflowPictureInputNode(ctx context.Context, wo
 			res = append(res, *v)
 		}
 	}
+	// 按节点 id 排序, 保证顺序稳定
+	sort.Slice(res, func(i, j int) bool {
+		a, b := gconv.Int(res[i].NodeId), gconv.Int(res[j].NodeId)
+		if a != b {
+			return a < b
+		}
+		return res[i].NodeId < res[j].NodeId
+	})
 	return res, nil
 }
 
+// UploadResourceV2 通过 v2 方式上传本地文件, 文件内容流式写入请求体
 func (c *RunningHubClient) UploadResourceV2(ctx context.Context, filePath string) (res *UploadResourceV2Res, err error) {
 	if !gfile.IsFile(filePath) {
 		return nil, errors.New("the filePath does not point to a file")
 	}
-	url := fmt.Sprintf("%s%s", c.url, uploadResource)
-
-	payload := &bytes.Buffer{}
-	writer := multipart.NewWriter(payload)
-	file, errFile1 := os.Open(filePath)
-	defer file.Close()
-	part1, errFile1 := writer.CreateFormFile("file", filepath.Base(""))
-	_, errFile1 = io.Copy(part1, file)
-	if errFile1 != nil {
-		return nil, err
+	md5Hex, cached := c.lookupUploadCacheByFile(ctx, uploadCacheV2, filePath)
+	if cached != nil {
+		return &UploadResourceV2Res{
+			Type:        cached.FileType,
+			DownloadUrl: cached.DownloadUrl,
+			FileName:    cached.FileName,
+			Size:        cached.Size,
+		}, nil
 	}
-	err = writer.Close()
+	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
-	header := map[string]string{
-		"Content-Type":  writer.FormDataContentType(),
-		"Host":          "www.runninghub.cn",
-		"Authorization": "Bearer " + c.ApiKey,
-	}
-	resp, err := c.doPostContentWithHeader(ctx, url, header, payload)
-	if resp == nil || resp.Data == nil {
-		return nil, fmt.Errorf("error resp: %+v", resp)
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
 	}
-	if err := json.Unmarshal(resp.Data, &res); err != nil {
-		return nil, fmt.Errorf("decode success data fail: %w", err)
+	res, err = c.UploadResourceV2FromStream(ctx, &UploadStreamInput{
+		FileName: filepath.Base(filePath),
+		Reader:   file,
+		Size:     stat.Size(),
+	})
+	if err != nil {
+		return nil, err
 	}
+	c.saveUploadCache(ctx, &UploadCacheEntry{
+		Version:     uploadCacheV2,
+		Md5Hex:      md5Hex,
+		FileName:    res.FileName,
+		FileType:    res.Type,
+		DownloadUrl: res.DownloadUrl,
+		Size:        res.Size,
+	})
 	return res, nil
 }
 
@@ -593,6 +609,7 @@ func (c *RunningHubClient) GetLoraUploadUrlDefine(ctx context.Context, loraName
 	return res, nil
 }
 
+// UploadLoraFile 上传 LoRA 文件, 只上传一次, 不读写本地记录; 需要重试或进度回调时使用 UploadLoraFileWithOption
 func (c *RunningHubClient) UploadLoraFile(ctx context.Context, key string, md5Hex string, filePath string) (res *UploadLoraFileRes, err error) {
 	if !gfile.IsFile(filePath) {
 		return nil, gerror.Newf("filePath(%s) does not point to a file", filePath)
@@ -600,33 +617,18 @@ func (c *RunningHubClient) UploadLoraFile(ctx context.Context, key string, md5He
 	if md5Hex == "" {
 		return nil, gerror.Newf("md5Hex must be not empty")
 	}
-	if key == "" {
-		return nil, gerror.New("key must be not empty")
-	}
-	for _, c := range key {
-		if !(c >= 'a' && c <= 'z' ||
-			c >= 'A' && c <= 'Z' ||
-			c == '_' ||
-			c >= '0' && c <= '9') {
-			return nil, gerror.Newf("key(%s) must be  a-z, A-Z, 0-9 or _", key)
-		}
-	}
-	res, err = c.GetLoraUploadUrl(ctx, key, md5Hex)
-	if err != nil {
-		return nil, err
-	}
-	// 打开文件
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-	header := map[string]string{
-		"Content-Type": "application/octet-stream",
-	}
-	err = c.doPutWithHeader(ctx, res.Url, header, file)
+	record, err := c.UploadLoraFileWithOption(ctx, &UploadLoraOption{
+		LoraName:   key,
+		FilePath:   filePath,
+		Md5Hex:     md5Hex,
+		MaxRetries: -1,
+		Force:      true,
+	})
 	if err != nil {
 		return nil, err
 	}
-	return res, nil
+	return &UploadLoraFileRes{
+		FileName: record.FileName,
+		Url:      record.UploadUrl,
+	}, nil
 }
@@ -0,0 +1,214 @@
+package runninghub_client_utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestClient 返回指向测试服务的客户端
+func newTestClient(t *testing.T, handler http.Handler) *RunningHubClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewClient(&RunningHubClientConfig{
+		UseHttpReq: true,
+		Host:       strings.TrimPrefix(server.URL, "http://"),
+		ApiKey:     "test-api-key",
+	})
+}
+
+// writeTestResponse 写入 RunningHub 通用响应
+func writeTestResponse(w http.ResponseWriter, code int, msg string, data interface{}) {
+	body, _ := json.Marshal(data)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&RunningHubResponse{Code: code, Msg: msg, Data: body})
+}
+
+// fakeTask 测试服务中的任务
+type fakeTask struct {
+	req       *CreateTaskReq
+	status    TaskStatus
+	polls     int
+	finishAt  int // 第几次查询状态时结束, 小于 0 时不会自行结束
+	final     TaskStatus
+	reason    *FailedReason
+	cancelled bool
+}
+
+// fakeRunningHub 模拟创建、查询、取消任务, 账户信息与 AI 应用接口
+type fakeRunningHub struct {
+	mu sync.Mutex
+	// CreateFailures 之后的创建请求返回 CreateCode 的次数
+	CreateFailures int
+	CreateCode     int
+	// FinishAt 新任务第几次查询状态时结束, 小于 0 时不会自行结束; FinishAts 不为空时依次用于新任务
+	FinishAt  int
+	FinishAts []int
+	// Final 新任务的结束状态, 默认 SUCCESS
+	Final  TaskStatus
+	Reason *FailedReason
+	// Money 成功任务的花费
+	Money string
+	// WebApps 可用的 AI 应用, 其他 webappId 返回 901
+	WebApps map[string]*GetWebAppInfoRes
+
+	tasks      map[string]*fakeTask
+	order      []string
+	creates    int
+	webAppReqs []*CreateWebAppTaskReq
+	webAppGets []url.Values
+}
+
+func newFakeRunningHub() *fakeRunningHub {
+	return &fakeRunningHub{
+		FinishAt: 1,
+		tasks:    make(map[string]*fakeTask),
+	}
+}
+
+func (f *fakeRunningHub) client(t *testing.T) *RunningHubClient {
+	return newTestClient(t, f)
+}
+
+// task 返回任务的副本
+func (f *fakeRunningHub) task(taskId string) fakeTask {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.tasks[taskId]
+}
+
+// taskIds 按创建顺序返回任务 id
+func (f *fakeRunningHub) taskIds() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.order...)
+}
+
+// createCount 返回创建请求次数
+func (f *fakeRunningHub) createCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.creates
+}
+
+// set 修改任务
+func (f *fakeRunningHub) set(taskId string, fn func(task *fakeTask)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(f.tasks[taskId])
+}
+
+// newTask 按配置创建任务, 调用方持有锁
+func (f *fakeRunningHub) newTask(req *CreateTaskReq) string {
+	taskId := fmt.Sprintf("task-%d", len(f.order)+1)
+	finishAt := f.FinishAt
+	if len(f.FinishAts) > 0 {
+		finishAt, f.FinishAts = f.FinishAts[0], f.FinishAts[1:]
+	}
+	f.tasks[taskId] = &fakeTask{req: req, status: TaskStatusQueued, finishAt: finishAt, final: f.Final, reason: f.Reason}
+	if f.tasks[taskId].final == TaskStatusUnknown {
+		f.tasks[taskId].final = TaskStatusSuccess
+	}
+	f.order = append(f.order, taskId)
+	return taskId
+}
+
+func (f *fakeRunningHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		CreateTaskReq
+		TaskId string `json:"taskId"`
+	}
+	raw, _ := io.ReadAll(r.Body)
+	json.Unmarshal(raw, &body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	task := f.tasks[body.TaskId]
+	if task == nil && strings.HasPrefix(r.URL.Path, "/task/openapi/") && r.URL.Path != createTask && r.URL.Path != createWebAppTask {
+		writeTestResponse(w, 807, "APIKEY_TASK_NOT_FOUND", nil)
+		return
+	}
+	switch r.URL.Path {
+	case createTask:
+		f.creates++
+		if f.CreateFailures > 0 {
+			f.CreateFailures--
+			writeTestResponse(w, f.CreateCode, "create fail", nil)
+			return
+		}
+		req := body.CreateTaskReq
+		taskId := f.newTask(&req)
+		writeTestResponse(w, 0, "success", &CreateTaskRes{TaskId: taskId, TaskStatus: string(TaskStatusQueued)})
+	case getWebAppInfo:
+		query := r.URL.Query()
+		f.webAppGets = append(f.webAppGets, query)
+		if info, ok := f.WebApps[query.Get("webappId")]; ok {
+			writeTestResponse(w, 0, "success", info)
+		} else {
+			writeTestResponse(w, 901, "WEBAPP_NOT_EXISTS", nil)
+		}
+	case createWebAppTask:
+		var req CreateWebAppTaskReq
+		json.Unmarshal(raw, &req)
+		f.webAppReqs = append(f.webAppReqs, &req)
+		if _, ok := f.WebApps[req.WebappId]; !ok {
+			writeTestResponse(w, 901, "WEBAPP_NOT_EXISTS", nil)
+			return
+		}
+		taskId := f.newTask(&CreateTaskReq{NodeInfoList: req.NodeInfoList})
+		writeTestResponse(w, 0, "success", &CreateTaskRes{TaskId: taskId, TaskStatus: string(TaskStatusQueued)})
+	case getTaskStatus:
+		task.polls++
+		if !task.status.IsTerminal() {
+			if task.finishAt >= 0 && task.polls >= task.finishAt {
+				task.status = task.final
+			} else {
+				task.status = TaskStatusRunning
+			}
+		}
+		writeTestResponse(w, 0, "success", task.status)
+	case getTaskResult:
+		switch task.status {
+		case TaskStatusSuccess:
+			writeTestResponse(w, 0, "success", []*SuccessOfGetTaskResultResponseData{
+				{FileUrl: "https://example.com/" + body.TaskId + ".png", FileType: "png", NodeId: "9", ConsumeMoney: f.Money},
+			})
+		case TaskStatusFailed:
+			writeTestResponse(w, codeTaskStatusError, "APIKEY_TASK_STATUS_ERROR", map[string]interface{}{"failedReason": task.reason})
+		default:
+			writeTestResponse(w, codeTaskIsRunning, "APIKEY_TASK_IS_RUNNING", nil)
+		}
+	case cancelTask:
+		if task.status.IsTerminal() {
+			writeTestResponse(w, 805, "APIKEY_TASK_STATUS_ERROR", nil)
+			return
+		}
+		task.cancelled = true
+		task.status = TaskStatusFailed
+		writeTestResponse(w, 0, "success", nil)
+	case getAccountInfo:
+		running := 0
+		for _, v := range f.tasks {
+			if !v.status.IsTerminal() {
+				running++
+			}
+		}
+		writeTestResponse(w, 0, "success", &GetAccountRes{RemainCoins: "100", RemainMoney: "10.5", CurrentTaskCounts: fmt.Sprint(running)})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// testCreateTaskReq 返回可以通过参数校验的创建任务请求
+func testCreateTaskReq() *CreateTaskReq {
+	return &CreateTaskReq{
+		WorkflowId:   "wf",
+		NodeInfoList: []*NodeInfo{{NodeId: "6", FieldName: "text", FieldValue: "a cat"}},
+	}
+}
@@ -0,0 +1,512 @@
+package runninghub_client_utils
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrJobPreempted 排队中的任务被移出队列, 未提交到平台
+var ErrJobPreempted = errors.New("scheduled job preempted before submission")
+
+type SchedulerConfig struct {
+	Client          *RunningHubClient
+	Budget          *Budget        // 不为空时通过预算守卫提交
+	InitialLimit    int            // 初始并发上限, 默认 1, 之后根据 CurrentTaskCounts 与 421 自动调整
+	MaxLimit        int            // 并发上限的最大值, 0 不限制
+	Weights         map[string]int // 租户权重, 默认 1
+	RefreshInterval time.Duration  // 通过 GetAccountInfo 刷新执行中任务数的间隔, 默认 5s
+	BackoffDelay    time.Duration  // 并发已满(421/415)后暂停派发的时间, 默认 10s
+	ProbeInterval   time.Duration  // 持续满载且未被拒绝时尝试提高上限的间隔, 默认 1 分钟
+
+	OnDispatch func(job *ScheduledJob) // 任务提交后回调, 无论成功与否
+	OnError    func(err error)         // 刷新账户信息失败时回调
+}
+
+// ScheduledJob 排队中或已提交的任务
+type ScheduledJob struct {
+	Id         int64
+	Tenant     string
+	Priority   int // 越大越先提交
+	Req        *CreateTaskReq
+	EnqueuedAt time.Time
+
+	ctx          context.Context
+	stopWatch    func() bool
+	index        int
+	dispatchedAt time.Time
+	done         chan struct{}
+	res          *CreateTaskRes
+	err          error
+}
+
+// Done 任务提交完成、失败或被移出队列时关闭
+func (j *ScheduledJob) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait 等待任务提交, ctx 取消只结束等待, 不影响排队
+func (j *ScheduledJob) Wait(ctx context.Context) (*CreateTaskRes, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-j.done:
+		return j.res, j.err
+	}
+}
+
+// DispatchedAt 提交时间, 排队中为零值
+func (j *ScheduledJob) DispatchedAt() time.Time {
+	select {
+	case <-j.done:
+		return j.dispatchedAt
+	default:
+		return time.Time{}
+	}
+}
+
+func (j *ScheduledJob) finish(res *CreateTaskRes, err error) {
+	j.res, j.err = res, err
+	close(j.done)
+}
+
+// scheduledJobHeap 单个租户的队列, 按优先级降序, 同优先级先进先出
+type scheduledJobHeap []*ScheduledJob
+
+func (h scheduledJobHeap) Len() int { return len(h) }
+func (h scheduledJobHeap) Less(i, j int) bool {
+	if h[i].Priority != h[j].Priority {
+		return h[i].Priority > h[j].Priority
+	}
+	return h[i].Id < h[j].Id
+}
+func (h scheduledJobHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+func (h *scheduledJobHeap) Push(x any) {
+	job := x.(*ScheduledJob)
+	job.index = len(*h)
+	*h = append(*h, job)
+}
+func (h *scheduledJobHeap) Pop() any {
+	old := *h
+	job := old[len(old)-1]
+	old[len(old)-1] = nil
+	job.index = -1
+	*h = old[:len(old)-1]
+	return job
+}
+
+type schedulerTenant struct {
+	name       string
+	weight     int
+	queue      scheduledJobHeap
+	served     float64 // 按权重折算的已派发数, 最小者优先
+	dispatched int
+	totalWait  time.Duration
+}
+
+// SchedulerTenantStats 租户队列统计
+type SchedulerTenantStats struct {
+	Weight     int           `json:"weight"`
+	Queued     int           `json:"queued"`
+	Dispatched int           `json:"dispatched"`
+	AvgWait    time.Duration `json:"avgWait"`    // 已提交任务的平均排队时间
+	OldestWait time.Duration `json:"oldestWait"` // 队列中最早任务已等待的时间
+}
+
+// SchedulerStats 调度器统计
+type SchedulerStats struct {
+	Limit       int                              `json:"limit"`   // 当前学习到的并发上限
+	Running     int                              `json:"running"` // 估算的执行中任务数
+	Queued      int                              `json:"queued"`
+	PausedUntil time.Time                        `json:"pausedUntil"`
+	Tenants     map[string]*SchedulerTenantStats `json:"tenants"`
+}
+
+// Scheduler 位于 CreateTask 之前的本地调度器, 按租户(默认为工作流 id)排队,
+// 在账户并发上限内按权重公平派发; 执行中任务数在每次刷新账户信息时校准
+type Scheduler struct {
+	cfg *SchedulerConfig
+
+	mu              sync.Mutex
+	tenants         map[string]*schedulerTenant
+	jobs            map[int64]*ScheduledJob
+	seq             int64
+	limit           int
+	running         int
+	refreshedAt     time.Time
+	pausedUntil     time.Time
+	limitChangedAt  time.Time
+	wake            chan struct{}
+	cancel          context.CancelFunc
+	done            chan struct{}
+	now             func() time.Time
+	capacityErrCode map[int]bool
+}
+
+func NewScheduler(in *SchedulerConfig) *Scheduler {
+	if in.InitialLimit <= 0 {
+		in.InitialLimit = 1
+	}
+	if in.MaxLimit > 0 && in.InitialLimit > in.MaxLimit {
+		in.InitialLimit = in.MaxLimit
+	}
+	if in.RefreshInterval <= 0 {
+		in.RefreshInterval = 5 * time.Second
+	}
+	if in.BackoffDelay <= 0 {
+		in.BackoffDelay = 10 * time.Second
+	}
+	if in.ProbeInterval <= 0 {
+		in.ProbeInterval = time.Minute
+	}
+	return &Scheduler{
+		cfg:             in,
+		tenants:         make(map[string]*schedulerTenant),
+		jobs:            make(map[int64]*ScheduledJob),
+		limit:           in.InitialLimit,
+		limitChangedAt:  time.Now(), // 首次提高上限前至少等待一个 ProbeInterval
+		wake:            make(chan struct{}, 1),
+		now:             time.Now,
+		capacityErrCode: map[int]bool{415: true, 421: true}, // 415 独占型实例不足, 421 共享型并发已满
+	}
+}
+
+// Submit 将任务加入租户队列, tenant 为空时使用工作流 id; 需调用 Start 或 Run 才会派发
+func (s *Scheduler) Submit(ctx context.Context, tenant string, priority int, req *CreateTaskReq) (*ScheduledJob, error) {
+	if req == nil {
+		return nil, gerror.New("create task request cannot be nil")
+	}
+	if tenant == "" {
+		tenant = req.WorkflowId
+	}
+	s.mu.Lock()
+	s.seq++
+	job := &ScheduledJob{
+		Id:         s.seq,
+		Tenant:     tenant,
+		Priority:   priority,
+		Req:        req,
+		EnqueuedAt: s.now(),
+		ctx:        ctx,
+		done:       make(chan struct{}),
+	}
+	t := s.tenantLocked(tenant)
+	if t.queue.Len() == 0 {
+		// 空闲租户重新入队时不累积历史份额, 避免突发占满
+		if minServed, ok := s.minActiveServedLocked(); ok && t.served < minServed {
+			t.served = minServed
+		}
+	}
+	s.enqueueLocked(t, job)
+	s.mu.Unlock()
+	s.signal()
+	return job, nil
+}
+
+// SetPriority 调整排队中任务的优先级, 任务已派发时返回 false
+func (s *Scheduler) SetPriority(jobId int64, priority int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[jobId]
+	if !ok {
+		return false
+	}
+	job.Priority = priority
+	heap.Fix(&s.tenants[job.Tenant].queue, job.index)
+	return true
+}
+
+// Preempt 将排队中的任务移出队列, 任务以 ErrJobPreempted 结束; 任务已派发时返回 false
+func (s *Scheduler) Preempt(jobId int64) bool {
+	return s.PreemptFunc(func(job *ScheduledJob) bool { return job.Id == jobId }) > 0
+}
+
+// PreemptFunc 移出所有满足条件的排队中任务, 返回移出数量
+func (s *Scheduler) PreemptFunc(fn func(job *ScheduledJob) bool) int {
+	s.mu.Lock()
+	var removed []*ScheduledJob
+	for _, job := range s.jobs {
+		if fn(job) {
+			s.removeLocked(job)
+			removed = append(removed, job)
+		}
+	}
+	s.mu.Unlock()
+	for _, job := range removed {
+		job.stopWatch()
+		job.finish(nil, ErrJobPreempted)
+	}
+	return len(removed)
+}
+
+// Stats 返回队列深度、等待时间与当前并发上限
+func (s *Scheduler) Stats() *SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	res := &SchedulerStats{
+		Limit:       s.limit,
+		Running:     s.running,
+		Queued:      len(s.jobs),
+		PausedUntil: s.pausedUntil,
+		Tenants:     make(map[string]*SchedulerTenantStats, len(s.tenants)),
+	}
+	for name, t := range s.tenants {
+		stats := &SchedulerTenantStats{
+			Weight:     t.weight,
+			Queued:     t.queue.Len(),
+			Dispatched: t.dispatched,
+		}
+		if t.dispatched > 0 {
+			stats.AvgWait = t.totalWait / time.Duration(t.dispatched)
+		}
+		for _, job := range t.queue {
+			if wait := now.Sub(job.EnqueuedAt); wait > stats.OldestWait {
+				stats.OldestWait = wait
+			}
+		}
+		res.Tenants[name] = stats
+	}
+	return res
+}
+
+// Start 在后台开始派发, 重复调用无效
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		s.Run(ctx)
+	}()
+}
+
+// Stop 停止派发并等待退出, 排队中的任务保留在队列中
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// Run 阻塞派发直到 ctx 取消
+func (s *Scheduler) Run(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		s.mu.Lock()
+		// 队列为空时无需刷新, 恢复派发前会先校准
+		refreshDue := len(s.jobs) > 0 && s.now().Sub(s.refreshedAt) >= s.cfg.RefreshInterval
+		s.mu.Unlock()
+		if refreshDue {
+			s.refresh(ctx)
+		}
+		for s.dispatchOne(ctx) {
+		}
+
+		s.mu.Lock()
+		next := s.refreshedAt.Add(s.cfg.RefreshInterval)
+		if len(s.jobs) == 0 {
+			next = s.now().Add(s.cfg.RefreshInterval)
+		}
+		if s.pausedUntil.After(s.now()) && s.pausedUntil.Before(next) {
+			next = s.pausedUntil
+		}
+		wait := next.Sub(s.now())
+		s.mu.Unlock()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(max(wait, 0))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// refresh 以账户的 CurrentTaskCounts 校准执行中任务数
+func (s *Scheduler) refresh(ctx context.Context) {
+	res, err := s.cfg.Client.GetAccountInfo(ctx)
+	var snapshot *BalanceSnapshot
+	if err == nil {
+		snapshot, err = ParseBalanceSnapshot(res)
+	}
+	s.mu.Lock()
+	s.refreshedAt = s.now()
+	if err != nil {
+		s.mu.Unlock()
+		if s.cfg.OnError != nil && ctx.Err() == nil {
+			s.cfg.OnError(err)
+		}
+		return
+	}
+	s.running = snapshot.CurrentTaskCounts
+	// 平台实际同时执行的任务数不低于该值
+	if s.running > s.limit && (s.cfg.MaxLimit <= 0 || s.running <= s.cfg.MaxLimit) {
+		s.limit = s.running
+		s.limitChangedAt = s.refreshedAt
+	}
+	s.mu.Unlock()
+}
+
+// dispatchOne 按公平份额提交一个任务, 没有可提交的任务或并发已满时返回 false
+func (s *Scheduler) dispatchOne(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	s.mu.Lock()
+	now := s.now()
+	if now.Before(s.pausedUntil) || len(s.jobs) == 0 {
+		s.mu.Unlock()
+		return false
+	}
+	if s.running >= s.limit {
+		// 持续满载且一段时间内未被拒绝, 尝试提高上限
+		if now.Sub(s.limitChangedAt) < s.cfg.ProbeInterval || s.cfg.MaxLimit > 0 && s.limit >= s.cfg.MaxLimit {
+			s.mu.Unlock()
+			return false
+		}
+		s.limit++
+		s.limitChangedAt = now
+	}
+	t := s.nextTenantLocked()
+	job := heap.Pop(&t.queue).(*ScheduledJob)
+	delete(s.jobs, job.Id)
+	t.served += 1 / float64(t.weight)
+	s.running++
+	s.mu.Unlock()
+	job.stopWatch()
+
+	if err := job.ctx.Err(); err != nil {
+		s.mu.Lock()
+		s.running--
+		s.mu.Unlock()
+		job.finish(nil, err)
+		return true
+	}
+	var (
+		res *CreateTaskRes
+		err error
+	)
+	if s.cfg.Budget != nil {
+		res, err = s.cfg.Budget.CreateTask(job.ctx, job.Req)
+	} else {
+		res, err = s.cfg.Client.CreateTask(job.ctx, job.Req)
+	}
+
+	s.mu.Lock()
+	if code, ok := ApiErrorCode(err); ok && s.capacityErrCode[code] {
+		// 并发已满: 任务放回队首, 按当前估算下调上限并暂停派发
+		s.running--
+		t.served -= 1 / float64(t.weight)
+		s.enqueueLocked(t, job)
+		s.limit = max(1, min(s.limit, s.running))
+		s.limitChangedAt = s.now()
+		s.pausedUntil = s.limitChangedAt.Add(s.cfg.BackoffDelay)
+		// 恢复派发前重新校准执行中任务数
+		s.refreshedAt = time.Time{}
+		s.mu.Unlock()
+		return false
+	}
+	if err != nil {
+		s.running--
+	} else {
+		job.dispatchedAt = s.now()
+		t.dispatched++
+		t.totalWait += job.dispatchedAt.Sub(job.EnqueuedAt)
+	}
+	s.mu.Unlock()
+	job.finish(res, err)
+	if s.cfg.OnDispatch != nil {
+		s.cfg.OnDispatch(job)
+	}
+	return true
+}
+
+func (s *Scheduler) tenantLocked(name string) *schedulerTenant {
+	t, ok := s.tenants[name]
+	if !ok {
+		t = &schedulerTenant{
+			name:   name,
+			weight: max(1, s.cfg.Weights[name]),
+		}
+		s.tenants[name] = t
+	}
+	return t
+}
+
+func (s *Scheduler) minActiveServedLocked() (minServed float64, ok bool) {
+	for _, t := range s.tenants {
+		if t.queue.Len() > 0 && (!ok || t.served < minServed) {
+			minServed, ok = t.served, true
+		}
+	}
+	return minServed, ok
+}
+
+// nextTenantLocked 选择按权重折算已派发数最少的非空租户, 相同时按名称
+func (s *Scheduler) nextTenantLocked() *schedulerTenant {
+	var candidates []*schedulerTenant
+	for _, t := range s.tenants {
+		if t.queue.Len() > 0 {
+			candidates = append(candidates, t)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].served != candidates[j].served {
+			return candidates[i].served < candidates[j].served
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	return candidates[0]
+}
+
+// enqueueLocked 加入租户队列, ctx 取消时自动移出
+func (s *Scheduler) enqueueLocked(t *schedulerTenant, job *ScheduledJob) {
+	heap.Push(&t.queue, job)
+	s.jobs[job.Id] = job
+	job.stopWatch = context.AfterFunc(job.ctx, func() {
+		s.mu.Lock()
+		_, queued := s.jobs[job.Id]
+		if queued {
+			s.removeLocked(job)
+		}
+		s.mu.Unlock()
+		if queued {
+			job.finish(nil, job.ctx.Err())
+		}
+	})
+}
+
+func (s *Scheduler) removeLocked(job *ScheduledJob) {
+	heap.Remove(&s.tenants[job.Tenant].queue, job.index)
+	delete(s.jobs, job.Id)
+}
+
+func (s *Scheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,236 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// schedulerReq 返回指定工作流的创建任务请求
+func schedulerReq(workflowId string) *CreateTaskReq {
+	req := testCreateTaskReq()
+	req.WorkflowId = workflowId
+	return req
+}
+
+func TestSchedulerFairShare(t *testing.T) {
+	tests := []struct {
+		name     string
+		weights  map[string]int
+		jobs     map[string]int // 租户 -> 排队任务数
+		dispatch int
+		want     map[string]int // 前 dispatch 个派发中各租户的数量
+	}{
+		{name: "equal weights", jobs: map[string]int{"a": 6, "b": 6, "c": 6}, dispatch: 9, want: map[string]int{"a": 3, "b": 3, "c": 3}},
+		{name: "weighted", weights: map[string]int{"a": 2}, jobs: map[string]int{"a": 10, "b": 10}, dispatch: 9, want: map[string]int{"a": 6, "b": 3}},
+		{name: "idle tenant gets the rest", weights: map[string]int{"a": 3}, jobs: map[string]int{"a": 2, "b": 10}, dispatch: 8, want: map[string]int{"a": 2, "b": 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeRunningHub()
+			var order []string
+			s := NewScheduler(&SchedulerConfig{
+				Client:       fake.client(t),
+				InitialLimit: 100,
+				Weights:      tt.weights,
+				OnDispatch:   func(job *ScheduledJob) { order = append(order, job.Tenant) },
+			})
+			ctx := context.Background()
+			for tenant, n := range tt.jobs {
+				for i := 0; i < n; i++ {
+					if _, err := s.Submit(ctx, tenant, 0, schedulerReq("wf-"+tenant)); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			for i := 0; i < tt.dispatch; i++ {
+				if !s.dispatchOne(ctx) {
+					t.Fatalf("dispatch %d returned false", i)
+				}
+			}
+			got := make(map[string]int)
+			for _, v := range order {
+				got[v]++
+			}
+			for tenant, want := range tt.want {
+				if got[tenant] != want {
+					t.Fatalf("dispatched %v (order %v), want %v", got, order, tt.want)
+				}
+			}
+			stats := s.Stats()
+			if stats.Running != tt.dispatch || stats.Queued != sumInts(tt.jobs)-tt.dispatch {
+				t.Fatalf("stats = %+v", stats)
+			}
+		})
+	}
+}
+
+func sumInts(m map[string]int) (res int) {
+	for _, v := range m {
+		res += v
+	}
+	return res
+}
+
+func TestSchedulerPriorityAndPreempt(t *testing.T) {
+	fake := newFakeRunningHub()
+	var order []string
+	s := NewScheduler(&SchedulerConfig{
+		Client:       fake.client(t),
+		InitialLimit: 100,
+		OnDispatch:   func(job *ScheduledJob) { order = append(order, job.Req.WorkflowId) },
+	})
+	ctx := context.Background()
+	submit := func(ctx context.Context, name string, priority int) *ScheduledJob {
+		job, err := s.Submit(ctx, "tenant", priority, schedulerReq(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return job
+	}
+	submit(ctx, "low", 0)
+	high := submit(ctx, "high", 5)
+	mid := submit(ctx, "mid", 1)
+	preempted := submit(ctx, "preempted", 9)
+	raised := submit(ctx, "raised", 0)
+	cancelCtx, cancel := context.WithCancel(ctx)
+	cancelled := submit(cancelCtx, "cancelled", 9)
+
+	if !s.Preempt(preempted.Id) {
+		t.Fatal("Preempt() = false")
+	}
+	if _, err := preempted.Wait(ctx); !errors.Is(err, ErrJobPreempted) {
+		t.Fatalf("preempted job err = %v", err)
+	}
+	if !s.SetPriority(raised.Id, 3) {
+		t.Fatal("SetPriority() = false")
+	}
+	cancel()
+	if _, err := cancelled.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled job err = %v", err)
+	}
+	for s.dispatchOne(ctx) {
+	}
+	if fmt.Sprint(order) != "[high raised mid low]" {
+		t.Fatalf("dispatch order = %v", order)
+	}
+	if res, err := high.Wait(ctx); err != nil || res.TaskId == "" || high.DispatchedAt().IsZero() {
+		t.Fatalf("high job = %+v, %v", res, err)
+	}
+	// 已派发的任务不能再调整或移出
+	if s.Preempt(mid.Id) || s.SetPriority(mid.Id, 1) {
+		t.Fatal("dispatched job changed")
+	}
+	if got := fake.createCount(); got != 4 {
+		t.Fatalf("creates = %d, want 4", got)
+	}
+}
+
+func TestSchedulerLimitLearning(t *testing.T) {
+	fake := newFakeRunningHub()
+	fake.FinishAt = -1
+	fake.CreateFailures, fake.CreateCode = 1, 421
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewScheduler(&SchedulerConfig{
+		Client:        fake.client(t),
+		InitialLimit:  3,
+		MaxLimit:      5,
+		BackoffDelay:  10 * time.Second,
+		ProbeInterval: time.Minute,
+	})
+	s.now = func() time.Time { return now }
+	s.limitChangedAt = now
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		if _, err := s.Submit(ctx, "", 0, testCreateTaskReq()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 421: 任务放回队列, 按执行中任务数下调上限并暂停派发
+	if s.dispatchOne(ctx) {
+		t.Fatal("dispatch after 421 returned true")
+	}
+	if stats := s.Stats(); stats.Limit != 1 || stats.Queued != 10 || !stats.PausedUntil.Equal(now.Add(10*time.Second)) {
+		t.Fatalf("stats after 421 = %+v", stats)
+	}
+	if s.dispatchOne(ctx) {
+		t.Fatal("dispatched while paused")
+	}
+	now = now.Add(11 * time.Second)
+	if !s.dispatchOne(ctx) || s.dispatchOne(ctx) {
+		t.Fatal("want exactly one dispatch within limit 1")
+	}
+
+	// 平台上还有其他来源的任务在执行, 刷新后上限学习为 CurrentTaskCounts
+	for i := 0; i < 3; i++ {
+		if _, err := fake.client(t).CreateTask(ctx, testCreateTaskReq()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.refresh(ctx)
+	if stats := s.Stats(); stats.Limit != 4 || stats.Running != 4 {
+		t.Fatalf("stats after refresh = %+v", stats)
+	}
+	if s.dispatchOne(ctx) {
+		t.Fatal("dispatched over the learned limit")
+	}
+	// 满载一个 ProbeInterval 后尝试提高上限, 不超过 MaxLimit
+	now = now.Add(time.Minute)
+	if !s.dispatchOne(ctx) || s.dispatchOne(ctx) {
+		t.Fatal("want exactly one probe dispatch")
+	}
+	now = now.Add(time.Minute)
+	if s.dispatchOne(ctx) {
+		t.Fatal("dispatched over MaxLimit")
+	}
+	if stats := s.Stats(); stats.Limit != 5 || stats.Running != 5 || stats.Queued != 8 {
+		t.Fatalf("final stats = %+v", stats)
+	}
+}
+
+func TestSchedulerConcurrentSubmit(t *testing.T) {
+	fake := newFakeRunningHub()
+	s := NewScheduler(&SchedulerConfig{
+		Client:          fake.client(t),
+		InitialLimit:    1000,
+		Weights:         map[string]int{"t0": 2},
+		RefreshInterval: 10 * time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	defer s.Stop()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 60)
+	for i := 0; i < 60; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			job, err := s.Submit(ctx, fmt.Sprintf("t%d", i%3), i%4, testCreateTaskReq())
+			if err == nil {
+				_, err = job.Wait(ctx)
+			}
+			if err != nil {
+				errs <- err
+			}
+			_ = s.Stats()
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	dispatched := 0
+	for _, v := range s.Stats().Tenants {
+		dispatched += v.Dispatched
+	}
+	if dispatched != 60 || fake.createCount() != 60 {
+		t.Fatalf("dispatched = %d, creates = %d", dispatched, fake.createCount())
+	}
+}
@@ -0,0 +1,140 @@
+package runninghub_client_utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Store 可插拔的键值存储, 上传缓存、任务记录等组件共用
+type Store interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte) error
+	Delete(ctx context.Context, key string) error
+	// Scan 按 key 升序遍历指定前缀的数据, fn 返回 false 时停止
+	Scan(ctx context.Context, prefix string, fn func(key string, value []byte) bool) error
+}
+
+// MemoryStore 内存存储, 进程退出后数据丢失; 读写时复制 value, 调用方可以继续修改传入或返回的切片
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		data: make(map[string][]byte),
+	}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (value []byte, ok bool, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok = s.data[key]
+	return bytes.Clone(value), ok, nil
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = bytes.Clone(value)
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+func (s *MemoryStore) Scan(ctx context.Context, prefix string, fn func(key string, value []byte) bool) error {
+	s.mu.RLock()
+	keys := make([]string, 0, len(s.data))
+	for k := range s.data {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	values := make([][]byte, len(keys))
+	for i, k := range keys {
+		values[i] = bytes.Clone(s.data[k])
+	}
+	s.mu.RUnlock()
+	for i, k := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !fn(k, values[i]) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// FileStore 以单个 JSON 文件持久化的存储, 每次写入后原子替换文件
+type FileStore struct {
+	MemoryStore
+	path string
+}
+
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		MemoryStore: MemoryStore{
+			data: make(map[string][]byte),
+		},
+		path: path,
+	}
+	body, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return s, nil
+	}
+	if err = json.Unmarshal(body, &s.data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) Set(ctx context.Context, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = bytes.Clone(value)
+	return s.flush()
+}
+
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+	return s.flush()
+}
+
+func (s *FileStore) flush() error {
+	body, err := json.Marshal(s.data)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+			return err
+		}
+	}
+	tmpPath := s.path + ".tmp"
+	if err = os.WriteFile(tmpPath, body, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
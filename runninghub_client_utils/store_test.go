@@ -0,0 +1,55 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreCopiesValues(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name  string
+		store func(t *testing.T) Store
+	}{
+		{name: "memory", store: func(t *testing.T) Store { return NewMemoryStore() }},
+		{name: "file", store: func(t *testing.T) Store {
+			s, err := NewFileStore(filepath.Join(dir, "store.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := tt.store(t)
+			value := []byte("value")
+			if err := store.Set(ctx, "k", value); err != nil {
+				t.Fatal(err)
+			}
+			// 修改传入的切片不影响已保存的值
+			value[0] = 'X'
+			got, ok, err := store.Get(ctx, "k")
+			if err != nil || !ok || string(got) != "value" {
+				t.Fatalf("Get() = %q, %v, %v", got, ok, err)
+			}
+			// 修改返回的切片不影响已保存的值
+			got[0] = 'Y'
+			err = store.Scan(ctx, "k", func(key string, value []byte) bool {
+				if string(value) != "value" {
+					t.Fatalf("Scan() value = %q", value)
+				}
+				value[0] = 'Z'
+				return true
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _, _ = store.Get(ctx, "k"); string(got) != "value" {
+				t.Fatalf("Get() after modify = %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// CancelResult 单个任务的取消结果
+type CancelResult struct {
+	TaskId          string     `json:"taskId"`
+	Status          TaskStatus `json:"status"`          // 取消失败时查询到的任务状态
+	AlreadyFinished bool       `json:"alreadyFinished"` // 任务在取消前已结束, 视为取消成功
+	Err             error      `json:"-"`
+}
+
+// Ok 取消成功或任务已结束
+func (r *CancelResult) Ok() bool {
+	return r.Err == nil
+}
+
+// inflightTasks 通过 TaskHandle 绑定且尚未观察到结束的任务, 只跟踪 TaskHandle 是因为其后台轮询保证任务结束后移除
+type inflightTasks struct {
+	mu    sync.Mutex
+	tasks map[string]struct{}
+}
+
+func newInflightTasks() *inflightTasks {
+	return &inflightTasks{
+		tasks: make(map[string]struct{}),
+	}
+}
+
+func (c *RunningHubClient) trackTask(taskId string) {
+	if c.inflight == nil || taskId == "" {
+		return
+	}
+	c.inflight.mu.Lock()
+	defer c.inflight.mu.Unlock()
+	c.inflight.tasks[taskId] = struct{}{}
+}
+
+func (c *RunningHubClient) untrackTask(taskId string) {
+	if c.inflight == nil {
+		return
+	}
+	c.inflight.mu.Lock()
+	defer c.inflight.mu.Unlock()
+	delete(c.inflight.tasks, taskId)
+}
+
+// InflightTasks 返回通过 StartTask / StartWebAppTask / NewTaskHandle 绑定且尚未观察到结束的任务 id
+func (c *RunningHubClient) InflightTasks() []string {
+	if c.inflight == nil {
+		return nil
+	}
+	c.inflight.mu.Lock()
+	defer c.inflight.mu.Unlock()
+	res := make([]string, 0, len(c.inflight.tasks))
+	for taskId := range c.inflight.tasks {
+		res = append(res, taskId)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// CancelTaskWithResult 取消任务, 取消失败时查询任务状态, 任务已结束时视为成功
+func (c *RunningHubClient) CancelTaskWithResult(ctx context.Context, taskId string) *CancelResult {
+	res := &CancelResult{TaskId: taskId}
+	err := c.CancelTask(ctx, taskId)
+	if err == nil || taskId == "" {
+		res.Err = err
+		return res
+	}
+	statusRes, statusErr := c.GetTaskStatus(ctx, taskId)
+	if statusErr != nil {
+		res.Err = err
+		return res
+	}
+	res.Status = TaskStatusFromResponse(statusRes.Code, statusRes.Data)
+	if res.Status.IsTerminal() {
+		c.untrackTask(taskId)
+		res.AlreadyFinished = true
+		return res
+	}
+	res.Err = err
+	return res
+}
+
+// CancelAll 并发取消 InflightTasks 返回的任务, 结果按任务 id 排序
+func (c *RunningHubClient) CancelAll(ctx context.Context) []*CancelResult {
+	taskIds := c.InflightTasks()
+	res := make([]*CancelResult, len(taskIds))
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, 8)
+	)
+	for i, taskId := range taskIds {
+		wg.Add(1)
+		go func(i int, taskId string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			res[i] = c.CancelTaskWithResult(ctx, taskId)
+		}(i, taskId)
+	}
+	wg.Wait()
+	return res
+}
@@ -0,0 +1,204 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"sync"
+	"time"
+)
+
+type TaskHandleOption struct {
+	GracePeriod   time.Duration // ctx 取消后等待任务自行结束的时间, 期间继续轮询, 默认 10s, 小于 0 时立即取消
+	PollInterval  time.Duration // 轮询间隔, 默认 5s
+	CancelTimeout time.Duration // 宽限期内的查询与远程取消请求的超时时间, 默认 30s
+	OnCancel      func(res *CancelResult)
+
+	// passive 为 true 时 ctx 取消前不轮询, 由调用方查询并通过 finish 结束, 如 Run 与 webhook
+	passive bool
+}
+
+// TaskHandle 绑定 ctx 的任务, 后台轮询直到任务结束;
+// ctx 取消后在宽限期内继续轮询, 任务仍未结束时自动取消远程任务
+type TaskHandle struct {
+	TaskId string
+
+	client    *RunningHubClient
+	ctx       context.Context
+	opt       TaskHandleOption
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu        sync.Mutex
+	status    TaskStatus // 最近一次观察到的状态
+	result    *GetTaskStatusAndResultRes
+	cancelRes *CancelResult
+}
+
+// StartTask 创建任务并返回绑定 ctx 的 TaskHandle
+func (c *RunningHubClient) StartTask(ctx context.Context, payloadData *CreateTaskReq, opt *TaskHandleOption) (handle *TaskHandle, err error) {
+	res, err := c.CreateTask(ctx, payloadData)
+	if err != nil {
+		return nil, err
+	}
+	return c.NewTaskHandle(ctx, res.TaskId, opt), nil
+}
+
+// NewTaskHandle 为已创建的任务绑定 ctx
+func (c *RunningHubClient) NewTaskHandle(ctx context.Context, taskId string, opt *TaskHandleOption) *TaskHandle {
+	h := &TaskHandle{
+		TaskId: taskId,
+		client: c,
+		ctx:    ctx,
+		done:   make(chan struct{}),
+	}
+	if opt != nil {
+		h.opt = *opt
+	}
+	if h.opt.GracePeriod == 0 {
+		h.opt.GracePeriod = 10 * time.Second
+	}
+	if h.opt.PollInterval <= 0 {
+		h.opt.PollInterval = 5 * time.Second
+	}
+	if h.opt.CancelTimeout <= 0 {
+		h.opt.CancelTimeout = 30 * time.Second
+	}
+	c.trackTask(taskId)
+	go h.watch()
+	return h
+}
+
+// Done 任务结束或已取消时关闭
+func (h *TaskHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Result 返回观察到的最终结果, 尚未结束或已取消时为 nil
+func (h *TaskHandle) Result() *GetTaskStatusAndResultRes {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.result
+}
+
+// CancelResult 返回取消结果, 未取消时为 nil
+func (h *TaskHandle) CancelResult() *CancelResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.cancelRes
+}
+
+// Wait 等待任务结束, ctx 取消时返回 ctx 的错误, 远程任务由宽限期后的自动取消处理
+func (h *TaskHandle) Wait() (res *GetTaskStatusAndResultRes, err error) {
+	select {
+	case <-h.done:
+	case <-h.ctx.Done():
+		return nil, h.ctx.Err()
+	}
+	if res = h.Result(); res != nil {
+		return res, nil
+	}
+	return nil, gerror.Newf("task(%s) cancelled", h.TaskId)
+}
+
+// observe 记录查询到的状态, 不合法的状态变化(如 SUCCESS -> RUNNING)返回错误, 调用方应忽略该次查询结果
+func (h *TaskHandle) observe(status TaskStatus) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := ValidateTaskStatusTransition(h.status, status); err != nil {
+		return err
+	}
+	if status != TaskStatusUnknown {
+		h.status = status
+	}
+	return nil
+}
+
+// finish 记录最终结果并结束 watch
+func (h *TaskHandle) finish(res *GetTaskStatusAndResultRes) {
+	h.mu.Lock()
+	if h.result == nil {
+		h.result = res
+	}
+	h.mu.Unlock()
+	h.client.untrackTask(h.TaskId)
+	h.close()
+}
+
+// Cancel 立即取消远程任务, 任务已结束时视为成功; 重复调用返回首次的结果
+func (h *TaskHandle) Cancel(ctx context.Context) *CancelResult {
+	h.mu.Lock()
+	if h.cancelRes != nil {
+		defer h.mu.Unlock()
+		return h.cancelRes
+	}
+	h.mu.Unlock()
+
+	res := h.client.CancelTaskWithResult(ctx, h.TaskId)
+	h.mu.Lock()
+	if h.cancelRes == nil {
+		h.cancelRes = res
+	}
+	res = h.cancelRes
+	h.mu.Unlock()
+	if h.opt.OnCancel != nil {
+		h.opt.OnCancel(res)
+	}
+	if res.Ok() {
+		h.client.untrackTask(h.TaskId)
+		h.close()
+	}
+	return res
+}
+
+func (h *TaskHandle) close() {
+	h.closeOnce.Do(func() {
+		close(h.done)
+	})
+}
+
+func (h *TaskHandle) watch() {
+	if !h.opt.passive && !h.poll(h.ctx, h.opt.PollInterval) {
+		return
+	}
+	select {
+	case <-h.done:
+		return
+	case <-h.ctx.Done():
+	}
+	// 原 ctx 已取消, 使用独立的 ctx 查询与发送取消请求
+	detached := context.WithoutCancel(h.ctx)
+	if h.opt.GracePeriod > 0 {
+		ctx, cancel := context.WithTimeout(detached, h.opt.GracePeriod)
+		finished := !h.poll(ctx, h.opt.PollInterval)
+		cancel()
+		if finished {
+			return
+		}
+	}
+	ctx, cancel := context.WithTimeout(detached, h.opt.CancelTimeout)
+	defer cancel()
+	h.Cancel(ctx)
+}
+
+// poll 轮询直到任务结束或 ctx 结束, 任务结束时返回 false
+func (h *TaskHandle) poll(ctx context.Context, interval time.Duration) bool {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		reqCtx, cancel := context.WithTimeout(ctx, h.opt.CancelTimeout)
+		res, err := h.client.GetTaskStatusAndResult(reqCtx, h.TaskId)
+		cancel()
+		// 查询接口偶发错误或状态回退时继续轮询
+		if err == nil && h.observe(res.Status) == nil && res.Status.IsTerminal() {
+			h.finish(res)
+			return false
+		}
+		select {
+		case <-h.done:
+			return false
+		case <-ctx.Done():
+			return true
+		case <-ticker.C:
+		}
+	}
+}
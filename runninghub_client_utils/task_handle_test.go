@@ -0,0 +1,146 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func waitDone(t *testing.T, h *TaskHandle) {
+	t.Helper()
+	select {
+	case <-h.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("task handle not done")
+	}
+}
+
+func TestTaskHandle(t *testing.T) {
+	tests := []struct {
+		name       string
+		finishAt   int
+		grace      time.Duration
+		cancel     bool          // 启动后取消 ctx
+		finishLate time.Duration // ctx 取消后多久让任务自行结束, 0 不结束
+		wantStatus TaskStatus    // Result 的状态, 为空表示无结果
+		wantRemote bool          // 远程任务被取消
+	}{
+		{name: "finishes without wait", finishAt: 3, grace: time.Second, wantStatus: TaskStatusSuccess},
+		{name: "finishes during grace period", finishAt: -1, grace: time.Second, cancel: true, finishLate: 50 * time.Millisecond, wantStatus: TaskStatusSuccess},
+		{name: "cancelled after grace period", finishAt: -1, grace: 50 * time.Millisecond, cancel: true, wantRemote: true},
+		{name: "cancelled immediately", finishAt: -1, grace: -1, cancel: true, wantRemote: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeRunningHub()
+			fake.FinishAt = tt.finishAt
+			client := fake.client(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var cancelRes *CancelResult
+			h, err := client.StartTask(ctx, testCreateTaskReq(), &TaskHandleOption{
+				GracePeriod:  tt.grace,
+				PollInterval: 10 * time.Millisecond,
+				OnCancel:     func(res *CancelResult) { cancelRes = res },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := client.InflightTasks(); len(got) != 1 || got[0] != h.TaskId {
+				t.Fatalf("InflightTasks() = %v", got)
+			}
+			if tt.cancel {
+				time.Sleep(30 * time.Millisecond)
+				cancel()
+				if _, err = h.Wait(); !errors.Is(err, context.Canceled) {
+					t.Fatalf("Wait() err = %v, want context.Canceled", err)
+				}
+				if tt.finishLate > 0 {
+					time.Sleep(tt.finishLate)
+					fake.set(h.TaskId, func(task *fakeTask) { task.finishAt = task.polls + 1 })
+				}
+			}
+			waitDone(t, h)
+			if res := h.Result(); tt.wantStatus == "" && res != nil || tt.wantStatus != "" && (res == nil || res.Status != tt.wantStatus) {
+				t.Fatalf("Result() = %+v, want status %q", res, tt.wantStatus)
+			}
+			if task := fake.task(h.TaskId); task.cancelled != tt.wantRemote {
+				t.Fatalf("remote cancelled = %v, want %v", task.cancelled, tt.wantRemote)
+			}
+			if tt.wantRemote && (cancelRes == nil || !cancelRes.Ok() || h.CancelResult() != cancelRes) {
+				t.Fatalf("cancel result = %+v", cancelRes)
+			}
+			if got := client.InflightTasks(); len(got) != 0 {
+				t.Fatalf("InflightTasks() after done = %v", got)
+			}
+		})
+	}
+}
+
+func TestTaskHandleWait(t *testing.T) {
+	fake := newFakeRunningHub()
+	fake.FinishAt = 2
+	client := fake.client(t)
+	h, err := client.StartTask(context.Background(), testCreateTaskReq(), &TaskHandleOption{PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := h.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != TaskStatusSuccess || len(res.SuccessItems) != 1 {
+		t.Fatalf("Wait() = %+v", res)
+	}
+	// 重复 Wait 返回相同结果
+	if again, err := h.Wait(); err != nil || again != res {
+		t.Fatalf("second Wait() = %p, %v", again, err)
+	}
+}
+
+func TestInflightTasksOnlyTracksHandles(t *testing.T) {
+	fake := newFakeRunningHub()
+	fake.FinishAt = -1
+	client := fake.client(t)
+	ctx := context.Background()
+	if _, err := client.CreateTask(ctx, testCreateTaskReq()); err != nil {
+		t.Fatal(err)
+	}
+	if got := client.InflightTasks(); len(got) != 0 {
+		t.Fatalf("CreateTask tracked %v", got)
+	}
+	handles := make([]*TaskHandle, 3)
+	for i := range handles {
+		h, err := client.StartTask(ctx, testCreateTaskReq(), &TaskHandleOption{PollInterval: time.Hour})
+		if err != nil {
+			t.Fatal(err)
+		}
+		handles[i] = h
+	}
+	// 其中一个任务在其他地方被观察到结束
+	fake.set(handles[0].TaskId, func(task *fakeTask) { task.status = TaskStatusSuccess })
+	if _, err := client.GetTaskStatus(ctx, handles[0].TaskId); err != nil {
+		t.Fatal(err)
+	}
+	results := client.CancelAll(ctx)
+	if len(results) != 2 {
+		t.Fatalf("CancelAll() = %d results, want 2", len(results))
+	}
+	for _, v := range results {
+		if !v.Ok() || v.AlreadyFinished {
+			t.Fatalf("cancel %s: %+v", v.TaskId, v)
+		}
+		if !fake.task(v.TaskId).cancelled {
+			t.Fatalf("task %s not cancelled", v.TaskId)
+		}
+	}
+	if got := client.InflightTasks(); len(got) != 0 {
+		t.Fatalf("InflightTasks() after CancelAll = %v", got)
+	}
+	// 已结束的任务取消视为成功
+	res := client.CancelTaskWithResult(ctx, handles[0].TaskId)
+	if !res.Ok() || !res.AlreadyFinished || res.Status != TaskStatusSuccess {
+		t.Fatalf("CancelTaskWithResult() = %+v", res)
+	}
+}
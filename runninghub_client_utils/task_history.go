@@ -0,0 +1,233 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TaskHistoryQuery 任务记录查询条件, 零值字段不过滤
+type TaskHistoryQuery struct {
+	WorkflowId      string
+	JobId           string
+	Status          []TaskStatus // 命中任意一个即可
+	FailureCategory string
+	Tags            []string  // 需包含全部标签
+	Since           time.Time // 记录时间不早于该时间, 记录时间取 CreatedAt, 为空时取 FinishedAt
+	Until           time.Time // 记录时间早于该时间
+	MinCost         Cost      // 花费不低于该值, 为 0 的项不检查
+	MaxCost         Cost      // 花费不高于该值, 为 0 的项不检查
+	Desc            bool      // 按记录时间倒序
+	Limit           int       // 返回条数上限, 0 不限制
+}
+
+// TaskHistoryGroup 分组统计
+type TaskHistoryGroup struct {
+	Count     int  `json:"count"`
+	Succeeded int  `json:"succeeded"`
+	Failed    int  `json:"failed"`
+	Cost      Cost `json:"cost"`
+}
+
+func (g *TaskHistoryGroup) add(record *TaskRecord) {
+	g.Count++
+	switch record.Status {
+	case TaskStatusSuccess:
+		g.Succeeded++
+	case TaskStatusFailed:
+		g.Failed++
+	}
+	g.Cost = g.Cost.Add(record.Cost)
+}
+
+// TaskHistorySummary 查询结果汇总
+type TaskHistorySummary struct {
+	TaskHistoryGroup
+	ByWorkflow        map[string]*TaskHistoryGroup `json:"byWorkflow"`
+	ByStatus          map[TaskStatus]int           `json:"byStatus"`
+	ByFailureCategory map[string]int               `json:"byFailureCategory"`
+}
+
+// TaskHistory 基于 TaskStore 的任务记录查询
+type TaskHistory struct {
+	store *TaskStore
+}
+
+func NewTaskHistory(store *TaskStore) *TaskHistory {
+	return &TaskHistory{
+		store: store,
+	}
+}
+
+// taskRecordTime 记录时间, CreatedAt 为空时取 FinishedAt
+func taskRecordTime(record *TaskRecord) time.Time {
+	if !record.CreatedAt.IsZero() {
+		return record.CreatedAt
+	}
+	return record.FinishedAt
+}
+
+func (q *TaskHistoryQuery) match(record *TaskRecord) bool {
+	if q.WorkflowId != "" && record.WorkflowId != q.WorkflowId {
+		return false
+	}
+	if q.JobId != "" && record.JobId != q.JobId {
+		return false
+	}
+	if len(q.Status) > 0 {
+		found := false
+		for _, v := range q.Status {
+			if record.Status == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.FailureCategory != "" && record.FailureCategory != q.FailureCategory {
+		return false
+	}
+	for _, tag := range q.Tags {
+		found := false
+		for _, v := range record.Tags {
+			if v == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	t := taskRecordTime(record)
+	if !q.Since.IsZero() && t.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !t.Before(q.Until) {
+		return false
+	}
+	if q.MinCost.Money > 0 && record.Cost.Money < q.MinCost.Money || q.MinCost.Coins > 0 && record.Cost.Coins < q.MinCost.Coins {
+		return false
+	}
+	if record.Cost.exceeds(q.MaxCost) {
+		return false
+	}
+	return true
+}
+
+// Query 按条件查询任务记录, 按记录时间排序
+func (h *TaskHistory) Query(ctx context.Context, q *TaskHistoryQuery) (records []*TaskRecord, err error) {
+	if q == nil {
+		q = &TaskHistoryQuery{}
+	}
+	err = h.store.Scan(ctx, func(record *TaskRecord) bool {
+		if q.match(record) {
+			records = append(records, record)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		ti, tj := taskRecordTime(records[i]), taskRecordTime(records[j])
+		if q.Desc {
+			return ti.After(tj)
+		}
+		return ti.Before(tj)
+	})
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[:q.Limit]
+	}
+	return records, nil
+}
+
+// Summarize 汇总查询结果的数量与花费
+func (h *TaskHistory) Summarize(ctx context.Context, q *TaskHistoryQuery) (*TaskHistorySummary, error) {
+	records, err := h.Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeTaskRecords(records), nil
+}
+
+// SummarizeTaskRecords 汇总任务记录的数量与花费
+func SummarizeTaskRecords(records []*TaskRecord) *TaskHistorySummary {
+	res := &TaskHistorySummary{
+		ByWorkflow:        make(map[string]*TaskHistoryGroup),
+		ByStatus:          make(map[TaskStatus]int),
+		ByFailureCategory: make(map[string]int),
+	}
+	for _, v := range records {
+		res.add(v)
+		group, ok := res.ByWorkflow[v.WorkflowId]
+		if !ok {
+			group = &TaskHistoryGroup{}
+			res.ByWorkflow[v.WorkflowId] = group
+		}
+		group.add(v)
+		res.ByStatus[v.Status]++
+		if v.FailureCategory != "" {
+			res.ByFailureCategory[v.FailureCategory]++
+		}
+	}
+	return res
+}
+
+var taskRecordCSVHeader = []string{
+	"jobId", "taskId", "attempt", "workflowId", "status", "code", "msg", "failureCategory",
+	"money", "coins", "tags", "createdAt", "finishedAt",
+}
+
+// WriteTaskRecordsCSV 导出 CSV, 不包含请求内容
+func WriteTaskRecordsCSV(w io.Writer, records []*TaskRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(taskRecordCSVHeader); err != nil {
+		return err
+	}
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	for _, v := range records {
+		err := writer.Write([]string{
+			v.JobId,
+			v.TaskId,
+			strconv.Itoa(v.Attempt),
+			v.WorkflowId,
+			string(v.Status),
+			strconv.Itoa(v.Code),
+			v.Msg,
+			v.FailureCategory,
+			strconv.FormatFloat(v.Cost.Money, 'f', -1, 64),
+			strconv.FormatFloat(v.Cost.Coins, 'f', -1, 64),
+			strings.Join(v.Tags, ";"),
+			formatTime(v.CreatedAt),
+			formatTime(v.FinishedAt),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteTaskRecordsJSON 导出 JSON 数组
+func WriteTaskRecordsJSON(w io.Writer, records []*TaskRecord) error {
+	if records == nil {
+		records = []*TaskRecord{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(records)
+}
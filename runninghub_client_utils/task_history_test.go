@@ -0,0 +1,132 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestTaskHistory(t *testing.T) (*TaskHistory, time.Time) {
+	t.Helper()
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewTaskStore(NewMemoryStore())
+	records := []*TaskRecord{
+		{TaskId: "task-1", WorkflowId: "wf-a", Status: TaskStatusSuccess, Tags: []string{"x", "y"}, Cost: Cost{Money: 0.5, Coins: 10}, CreatedAt: t0},
+		{TaskId: "task-2", WorkflowId: "wf-a", Status: TaskStatusFailed, Code: 805, Msg: "out of memory, retry", FailureCategory: "oom", Tags: []string{"x"}, CreatedAt: t0.Add(time.Hour)},
+		// CreatedAt 为空时按 FinishedAt 过滤与排序
+		{TaskId: "task-3", WorkflowId: "wf-b", Status: TaskStatusSuccess, Cost: Cost{Money: 1.5, Coins: 30}, FinishedAt: t0.Add(2 * time.Hour)},
+		{JobId: "job-1", TaskId: "task-4", Attempt: 2, WorkflowId: "wf-b", Status: TaskStatusRunning, CreatedAt: t0.Add(3 * time.Hour)},
+	}
+	for _, v := range records {
+		if err := store.Save(context.Background(), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewTaskHistory(store), t0
+}
+
+func taskIdsOf(records []*TaskRecord) string {
+	ids := make([]string, 0, len(records))
+	for _, v := range records {
+		ids = append(ids, v.TaskId)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestTaskHistoryQuery(t *testing.T) {
+	history, t0 := newTestTaskHistory(t)
+	tests := []struct {
+		name string
+		q    *TaskHistoryQuery
+		want string
+	}{
+		{name: "all", q: nil, want: "task-1,task-2,task-3,task-4"},
+		{name: "desc with limit", q: &TaskHistoryQuery{Desc: true, Limit: 2}, want: "task-4,task-3"},
+		{name: "workflow", q: &TaskHistoryQuery{WorkflowId: "wf-b"}, want: "task-3,task-4"},
+		{name: "job", q: &TaskHistoryQuery{JobId: "job-1"}, want: "task-4"},
+		{name: "status any of", q: &TaskHistoryQuery{Status: []TaskStatus{TaskStatusFailed, TaskStatusRunning}}, want: "task-2,task-4"},
+		{name: "failure category", q: &TaskHistoryQuery{FailureCategory: "oom"}, want: "task-2"},
+		{name: "one tag", q: &TaskHistoryQuery{Tags: []string{"x"}}, want: "task-1,task-2"},
+		{name: "all tags", q: &TaskHistoryQuery{Tags: []string{"y", "x"}}, want: "task-1"},
+		{name: "missing tag", q: &TaskHistoryQuery{Tags: []string{"x", "z"}}, want: ""},
+		{name: "since is inclusive", q: &TaskHistoryQuery{Since: t0.Add(time.Hour)}, want: "task-2,task-3,task-4"},
+		{name: "until is exclusive", q: &TaskHistoryQuery{Until: t0.Add(2 * time.Hour)}, want: "task-1,task-2"},
+		{name: "finishedAt fallback", q: &TaskHistoryQuery{Since: t0.Add(2 * time.Hour), Until: t0.Add(2*time.Hour + time.Nanosecond)}, want: "task-3"},
+		{name: "min money is inclusive", q: &TaskHistoryQuery{MinCost: Cost{Money: 0.5}}, want: "task-1,task-3"},
+		{name: "max money is inclusive", q: &TaskHistoryQuery{MaxCost: Cost{Money: 0.5}}, want: "task-1,task-2,task-4"},
+		{name: "min coins", q: &TaskHistoryQuery{MinCost: Cost{Coins: 20}}, want: "task-3"},
+		{name: "max coins", q: &TaskHistoryQuery{MaxCost: Cost{Coins: 20}}, want: "task-1,task-2,task-4"},
+		{name: "cost range", q: &TaskHistoryQuery{MinCost: Cost{Money: 0.1}, MaxCost: Cost{Money: 1}}, want: "task-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := history.Query(context.Background(), tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := taskIdsOf(records); got != tt.want {
+				t.Fatalf("Query() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeTaskRecords(t *testing.T) {
+	history, _ := newTestTaskHistory(t)
+	res, err := history.Summarize(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Count != 4 || res.Succeeded != 2 || res.Failed != 1 || res.Cost != (Cost{Money: 2, Coins: 40}) {
+		t.Fatalf("summary = %+v", res.TaskHistoryGroup)
+	}
+	if a := res.ByWorkflow["wf-a"]; a == nil || *a != (TaskHistoryGroup{Count: 2, Succeeded: 1, Failed: 1, Cost: Cost{Money: 0.5, Coins: 10}}) {
+		t.Fatalf("wf-a = %+v", a)
+	}
+	if b := res.ByWorkflow["wf-b"]; b == nil || *b != (TaskHistoryGroup{Count: 2, Succeeded: 1, Cost: Cost{Money: 1.5, Coins: 30}}) {
+		t.Fatalf("wf-b = %+v", b)
+	}
+	if res.ByStatus[TaskStatusSuccess] != 2 || res.ByStatus[TaskStatusFailed] != 1 || res.ByStatus[TaskStatusRunning] != 1 {
+		t.Fatalf("byStatus = %v", res.ByStatus)
+	}
+	if len(res.ByFailureCategory) != 1 || res.ByFailureCategory["oom"] != 1 {
+		t.Fatalf("byFailureCategory = %v", res.ByFailureCategory)
+	}
+
+	empty := SummarizeTaskRecords(nil)
+	if empty.Count != 0 || empty.ByWorkflow == nil || empty.ByStatus == nil {
+		t.Fatalf("empty summary = %+v", empty)
+	}
+}
+
+func TestWriteTaskRecords(t *testing.T) {
+	history, _ := newTestTaskHistory(t)
+	records, err := history.Query(context.Background(), &TaskHistoryQuery{WorkflowId: "wf-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	if err = WriteTaskRecordsCSV(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	want := "jobId,taskId,attempt,workflowId,status,code,msg,failureCategory,money,coins,tags,createdAt,finishedAt\n" +
+		"task-1,task-1,1,wf-a,SUCCESS,0,,,0.5,10,x;y,2026-01-01T00:00:00Z,\n" +
+		"task-2,task-2,1,wf-a,FAILED,805,\"out of memory, retry\",oom,0,0,x,2026-01-01T01:00:00Z,\n"
+	if buf.String() != want {
+		t.Fatalf("csv =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err = WriteTaskRecordsCSV(&buf, nil); err != nil || strings.Count(buf.String(), "\n") != 1 {
+		t.Fatalf("empty csv = %q, %v", buf.String(), err)
+	}
+	buf.Reset()
+	if err = WriteTaskRecordsJSON(&buf, nil); err != nil || buf.String() != "[]\n" {
+		t.Fatalf("empty json = %q, %v", buf.String(), err)
+	}
+	buf.Reset()
+	if err = WriteTaskRecordsJSON(&buf, records[:1]); err != nil || !strings.Contains(buf.String(), `"taskId": "task-1"`) {
+		t.Fatalf("json = %q, %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,94 @@
+package runninghub_client_utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TaskStatus 任务状态
+type TaskStatus string
+
+const (
+	TaskStatusUnknown TaskStatus = ""
+	TaskStatusQueued  TaskStatus = "QUEUED"
+	TaskStatusRunning TaskStatus = "RUNNING"
+	TaskStatusSuccess TaskStatus = "SUCCESS"
+	TaskStatusFailed  TaskStatus = "FAILED"
+)
+
+const (
+	codeTaskIsRunning   = 804
+	codeTaskStatusError = 805
+	codeTaskIsQueued    = 813
+)
+
+// ParseTaskStatus 解析状态字符串, 忽略大小写与首尾空白
+func ParseTaskStatus(s string) (status TaskStatus, ok bool) {
+	status = TaskStatus(strings.ToUpper(strings.TrimSpace(s)))
+	switch status {
+	case TaskStatusQueued, TaskStatusRunning, TaskStatusSuccess, TaskStatusFailed:
+		return status, true
+	}
+	return TaskStatusUnknown, false
+}
+
+// TaskStatusFromResponse 由状态接口的 code 与 data 推导任务状态;
+// 804/813 表示任务执行中/排队中, 805 表示任务失败, 其余非 0 code 属于接口错误, 返回 TaskStatusUnknown
+func TaskStatusFromResponse(code int, data string) TaskStatus {
+	switch code {
+	case 0:
+		status, _ := ParseTaskStatus(data)
+		return status
+	case codeTaskIsRunning:
+		return TaskStatusRunning
+	case codeTaskIsQueued:
+		return TaskStatusQueued
+	case codeTaskStatusError:
+		return TaskStatusFailed
+	}
+	return TaskStatusUnknown
+}
+
+func (s TaskStatus) String() string {
+	return string(s)
+}
+
+// IsTerminal 任务是否已结束
+func (s TaskStatus) IsTerminal() bool {
+	return s == TaskStatusSuccess || s == TaskStatusFailed
+}
+
+// rank 状态先后顺序, 状态只能保持或前进
+func (s TaskStatus) rank() int {
+	switch s {
+	case TaskStatusQueued:
+		return 1
+	case TaskStatusRunning:
+		return 2
+	case TaskStatusSuccess, TaskStatusFailed:
+		return 3
+	}
+	return 0
+}
+
+// TaskStatusTransitionError 非法的状态变化
+type TaskStatusTransitionError struct {
+	From TaskStatus
+	To   TaskStatus
+}
+
+func (e *TaskStatusTransitionError) Error() string {
+	return fmt.Sprintf("invalid task status transition: %s -> %s", e.From, e.To)
+}
+
+// ValidateTaskStatusTransition 校验状态变化是否合法, 例如 SUCCESS -> RUNNING 与 SUCCESS -> FAILED 均不合法;
+// 未知状态可以变为任意状态, 任意状态也可以因接口错误暂时变为未知
+func ValidateTaskStatusTransition(from TaskStatus, to TaskStatus) error {
+	if from == TaskStatusUnknown || to == TaskStatusUnknown || from == to {
+		return nil
+	}
+	if from.IsTerminal() || to.rank() < from.rank() {
+		return &TaskStatusTransitionError{From: from, To: to}
+	}
+	return nil
+}
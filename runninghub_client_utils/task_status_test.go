@@ -0,0 +1,148 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestParseTaskStatus(t *testing.T) {
+	tests := map[string]TaskStatus{
+		"SUCCESS":   TaskStatusSuccess,
+		" running ": TaskStatusRunning,
+		"queued":    TaskStatusQueued,
+		"Failed":    TaskStatusFailed,
+		"":          TaskStatusUnknown,
+		"CANCELLED": TaskStatusUnknown,
+	}
+	for in, want := range tests {
+		got, ok := ParseTaskStatus(in)
+		if got != want || ok != (want != TaskStatusUnknown) {
+			t.Errorf("ParseTaskStatus(%q) = %q, %v, want %q", in, got, ok, want)
+		}
+	}
+}
+
+func TestTaskStatusFromResponse(t *testing.T) {
+	tests := []struct {
+		code int
+		data string
+		want TaskStatus
+	}{
+		{0, `SUCCESS`, TaskStatusSuccess},
+		{0, `running`, TaskStatusRunning},
+		{0, `FAILED`, TaskStatusFailed},
+		{0, ``, TaskStatusUnknown},
+		{0, `SOMETHING`, TaskStatusUnknown},
+		{codeTaskIsRunning, ``, TaskStatusRunning},
+		{codeTaskIsQueued, ``, TaskStatusQueued},
+		{codeTaskStatusError, ``, TaskStatusFailed},
+		// 接口错误不是任务失败
+		{301, ``, TaskStatusUnknown},
+		{807, ``, TaskStatusUnknown},
+		{1000, `FAILED`, TaskStatusUnknown},
+	}
+	for _, tt := range tests {
+		if got := TaskStatusFromResponse(tt.code, tt.data); got != tt.want {
+			t.Errorf("TaskStatusFromResponse(%d, %q) = %q, want %q", tt.code, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTaskStatusTransition(t *testing.T) {
+	tests := []struct {
+		from, to TaskStatus
+		ok       bool
+	}{
+		{TaskStatusUnknown, TaskStatusSuccess, true},
+		{TaskStatusQueued, TaskStatusRunning, true},
+		{TaskStatusQueued, TaskStatusSuccess, true},
+		{TaskStatusRunning, TaskStatusFailed, true},
+		{TaskStatusRunning, TaskStatusRunning, true},
+		{TaskStatusSuccess, TaskStatusSuccess, true},
+		{TaskStatusRunning, TaskStatusUnknown, true},
+		{TaskStatusSuccess, TaskStatusUnknown, true},
+		{TaskStatusRunning, TaskStatusQueued, false},
+		{TaskStatusSuccess, TaskStatusRunning, false},
+		{TaskStatusSuccess, TaskStatusFailed, false},
+		{TaskStatusFailed, TaskStatusSuccess, false},
+		{TaskStatusFailed, TaskStatusQueued, false},
+	}
+	for _, tt := range tests {
+		err := ValidateTaskStatusTransition(tt.from, tt.to)
+		if (err == nil) != tt.ok {
+			t.Errorf("ValidateTaskStatusTransition(%q, %q) = %v, want ok %v", tt.from, tt.to, err, tt.ok)
+		}
+	}
+}
+
+func TestTaskStatusTransitionApplied(t *testing.T) {
+	// TaskHandle 忽略状态回退, 保留最近一次合法的状态
+	h := &TaskHandle{}
+	for _, v := range []struct {
+		status TaskStatus
+		ok     bool
+		want   TaskStatus
+	}{
+		{TaskStatusQueued, true, TaskStatusQueued},
+		{TaskStatusRunning, true, TaskStatusRunning},
+		{TaskStatusQueued, false, TaskStatusRunning},
+		{TaskStatusUnknown, true, TaskStatusRunning},
+		{TaskStatusSuccess, true, TaskStatusSuccess},
+		{TaskStatusRunning, false, TaskStatusSuccess},
+	} {
+		if err := h.observe(v.status); (err == nil) != v.ok || h.status != v.want {
+			t.Fatalf("observe(%q) = %v, status %q, want ok %v status %q", v.status, err, h.status, v.ok, v.want)
+		}
+	}
+
+	// TaskStore 拒绝不合法的状态变化且不覆盖原记录
+	ctx := context.Background()
+	store := NewTaskStore(NewMemoryStore())
+	record := &TaskRecord{TaskId: "task-1", Status: TaskStatusRunning}
+	if err := store.Save(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+	record.Status = TaskStatusSuccess
+	if err := store.Save(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+	record.Status = TaskStatusRunning
+	var transitionErr *TaskStatusTransitionError
+	if err := store.Save(ctx, record); !errors.As(err, &transitionErr) || transitionErr.From != TaskStatusSuccess {
+		t.Fatalf("Save() err = %v, want *TaskStatusTransitionError", err)
+	}
+	if got, _, err := store.Get(ctx, "task-1"); err != nil || got.Status != TaskStatusSuccess {
+		t.Fatalf("Get() = %+v, %v", got, err)
+	}
+}
+
+func TestGetTaskStatusAndResultWithRetryApiError(t *testing.T) {
+	tests := []struct {
+		name string
+		code int
+		want TaskStatus
+	}{
+		{name: "running", code: codeTaskIsRunning, want: TaskStatusRunning},
+		{name: "queued", code: codeTaskIsQueued, want: TaskStatusQueued},
+		{name: "api error", code: 1000, want: TaskStatusUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != getTaskStatus {
+					t.Errorf("unexpected request %s", r.URL.Path)
+				}
+				writeTestResponse(w, tt.code, "msg", nil)
+			}))
+			res, err := client.GetTaskStatusAndResultWithRetry(context.Background(), &GetTaskStatusAndResultReqWithRetry{TaskId: "task-1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Status != tt.want || res.Code != tt.code {
+				t.Fatalf("result = %+v, want status %q", res, tt.want)
+			}
+		})
+	}
+}
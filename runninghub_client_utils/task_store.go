@@ -0,0 +1,143 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"strings"
+	"time"
+)
+
+// TaskRecord 任务记录, 同一作业的多次提交共享 JobId
+type TaskRecord struct {
+	JobId           string         `json:"jobId"`
+	TaskId          string         `json:"taskId"`
+	Attempt         int            `json:"attempt"` // 从 1 开始
+	WorkflowId      string         `json:"workflowId"`
+	Request         *CreateTaskReq `json:"request"` // 不含 ApiKey
+	Status          TaskStatus     `json:"status"`
+	Code            int            `json:"code"`
+	Msg             string         `json:"msg"`
+	FailureCategory string         `json:"failureCategory"`
+	Cost            Cost           `json:"cost"`
+	Tags            []string       `json:"tags"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	FinishedAt      time.Time      `json:"finishedAt"`
+}
+
+// TaskStore 基于 Store 的任务记录存储
+type TaskStore struct {
+	store Store
+}
+
+func NewTaskStore(store Store) *TaskStore {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &TaskStore{
+		store: store,
+	}
+}
+
+func taskRecordKey(taskId string) string {
+	return "task:" + taskId
+}
+
+// jobKeyEscaper 转义作业 id 中的 ":", 避免 a 的前缀匹配到 a:b 的索引; 不含 ":" 与 "%" 的 id 保持不变
+var jobKeyEscaper = strings.NewReplacer("%", "%25", ":", "%3A")
+
+func jobKeyPrefix(jobId string) string {
+	return "job:" + jobKeyEscaper.Replace(jobId) + ":"
+}
+
+func jobAttemptKey(jobId string, attempt int) string {
+	return fmt.Sprintf("%s%06d", jobKeyPrefix(jobId), attempt)
+}
+
+// Save 保存任务记录, 并建立作业到任务的索引;
+// 已有记录时校验状态变化, 不合法时(如 SUCCESS -> RUNNING)返回 *TaskStatusTransitionError 且不写入
+func (s *TaskStore) Save(ctx context.Context, record *TaskRecord) error {
+	if record == nil || record.TaskId == "" {
+		return gerror.New("task record taskId cannot be empty")
+	}
+	old, ok, err := s.Get(ctx, record.TaskId)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if err = ValidateTaskStatusTransition(old.Status, record.Status); err != nil {
+			return err
+		}
+	}
+	if record.JobId == "" {
+		record.JobId = record.TaskId
+	}
+	if record.Attempt <= 0 {
+		record.Attempt = 1
+	}
+	if record.Request != nil && record.Request.ApiKey != "" {
+		req := *record.Request
+		req.ApiKey = ""
+		record.Request = &req
+	}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err = s.store.Set(ctx, taskRecordKey(record.TaskId), value); err != nil {
+		return err
+	}
+	return s.store.Set(ctx, jobAttemptKey(record.JobId, record.Attempt), []byte(record.TaskId))
+}
+
+// Get 获取任务记录
+func (s *TaskStore) Get(ctx context.Context, taskId string) (record *TaskRecord, ok bool, err error) {
+	value, ok, err := s.store.Get(ctx, taskRecordKey(taskId))
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	if err = json.Unmarshal(value, &record); err != nil {
+		return nil, false, err
+	}
+	return record, record != nil, nil
+}
+
+// ListJob 按提交顺序返回作业的所有任务记录
+func (s *TaskStore) ListJob(ctx context.Context, jobId string) (records []*TaskRecord, err error) {
+	var taskIds []string
+	err = s.store.Scan(ctx, jobKeyPrefix(jobId), func(key string, value []byte) bool {
+		taskIds = append(taskIds, string(value))
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, taskId := range taskIds {
+		record, ok, err := s.Get(ctx, taskId)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// Scan 遍历所有任务记录, fn 返回 false 时停止
+func (s *TaskStore) Scan(ctx context.Context, fn func(record *TaskRecord) bool) error {
+	var decodeErr error
+	err := s.store.Scan(ctx, "task:", func(key string, value []byte) bool {
+		var record *TaskRecord
+		if err := json.Unmarshal(value, &record); err != nil {
+			decodeErr = gerror.Wrapf(err, "decode task record(%s) fail", strings.TrimPrefix(key, "task:"))
+			return false
+		}
+		return fn(record)
+	})
+	if err != nil {
+		return err
+	}
+	return decodeErr
+}
@@ -0,0 +1,56 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTaskStoreListJob(t *testing.T) {
+	ctx := context.Background()
+	store := NewTaskStore(NewMemoryStore())
+	// 提交次数乱序写入, 作业 id 互为前缀或包含 ":" 与 "%"
+	records := []*TaskRecord{
+		{JobId: "a", TaskId: "a-2", Attempt: 2},
+		{JobId: "a", TaskId: "a-10", Attempt: 10},
+		{JobId: "a", TaskId: "a-1", Attempt: 1},
+		{JobId: "a:b", TaskId: "ab-1", Attempt: 1},
+		{JobId: "a%3Ab", TaskId: "escaped-1", Attempt: 1},
+		{JobId: "ab", TaskId: "ab2-1", Attempt: 1},
+		{TaskId: "single"},
+	}
+	for _, v := range records {
+		if err := store.Save(ctx, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		jobId string
+		want  string
+	}{
+		{jobId: "a", want: "a-1:1,a-2:2,a-10:10"},
+		{jobId: "a:b", want: "ab-1:1"},
+		{jobId: "a%3Ab", want: "escaped-1:1"},
+		{jobId: "ab", want: "ab2-1:1"},
+		// 未指定 JobId 时以 TaskId 作为作业 id, 从第 1 次开始
+		{jobId: "single", want: "single:1"},
+		{jobId: "missing", want: ""},
+	}
+	for _, tt := range tests {
+		list, err := store.ListJob(ctx, tt.jobId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, v := range list {
+			if v.JobId != tt.jobId {
+				t.Fatalf("ListJob(%s) returned job %s", tt.jobId, v.JobId)
+			}
+			got = append(got, fmt.Sprintf("%s:%d", v.TaskId, v.Attempt))
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Fatalf("ListJob(%s) = %v, want %s", tt.jobId, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/Friday-fighting/runninghub_tools/utility"
+	"github.com/Friday-fighting/runninghub_tools/utility/hash"
+	"strings"
+	"time"
+)
+
+const (
+	uploadCacheV1 = "v1"
+	uploadCacheV2 = "v2"
+)
+
+// UploadCacheEntry 按内容哈希记录的上传结果
+type UploadCacheEntry struct {
+	Scope       string    `json:"scope"`   // 上传所属的 host 与账号, 见 UploadCacheScope
+	Version     string    `json:"version"` // v1: UploadResource, v2: UploadResourceV2
+	Md5Hex      string    `json:"md5Hex"`
+	FileName    string    `json:"fileName"`
+	FileType    string    `json:"fileType"`
+	DownloadUrl string    `json:"downloadUrl"`
+	Size        string    `json:"size"`
+	UploadedAt  time.Time `json:"uploadedAt"`
+}
+
+// UploadCache 上传去重缓存, 相同账号的相同内容在有效期内不再重复上传;
+// 只有内存与本地文件上传会查询缓存, 链接与 Reader 上传需先读完内容才能计算哈希, 总是直接上传
+type UploadCache struct {
+	store  Store
+	expire time.Duration
+}
+
+// NewUploadCache 创建上传缓存, expire 小于等于 0 时默认 24 小时
+func NewUploadCache(store Store, expire time.Duration) *UploadCache {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	if expire <= 0 {
+		expire = 24 * time.Hour
+	}
+	return &UploadCache{
+		store:  store,
+		expire: expire,
+	}
+}
+
+// UploadCacheScope 上传缓存的隔离范围, 由 host 与 api key 的哈希组成, 多个账号共用 Store 时互不可见
+func UploadCacheScope(host string, apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return fmt.Sprintf("%s/%s", strings.ToLower(host), hex.EncodeToString(sum[:8]))
+}
+
+func uploadCacheKey(scope string, version string, md5Hex string) string {
+	return fmt.Sprintf("upload:%s:%s:%s", scope, version, md5Hex)
+}
+
+// Get 获取 scope 下未过期的上传记录
+func (u *UploadCache) Get(ctx context.Context, scope string, version string, md5Hex string) (entry *UploadCacheEntry, ok bool) {
+	if u == nil || md5Hex == "" {
+		return nil, false
+	}
+	key := uploadCacheKey(scope, version, md5Hex)
+	value, ok, err := u.store.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, false
+	}
+	if err = json.Unmarshal(value, &entry); err != nil || entry == nil || entry.Scope != scope {
+		return nil, false
+	}
+	if time.Since(entry.UploadedAt) > u.expire {
+		u.store.Delete(ctx, key)
+		return nil, false
+	}
+	return entry, true
+}
+
+// Set 记录上传结果
+func (u *UploadCache) Set(ctx context.Context, entry *UploadCacheEntry) error {
+	if u == nil || entry == nil || entry.Md5Hex == "" {
+		return nil
+	}
+	if entry.UploadedAt.IsZero() {
+		entry.UploadedAt = time.Now()
+	}
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return u.store.Set(ctx, uploadCacheKey(entry.Scope, entry.Version, entry.Md5Hex), value)
+}
+
+// uploadCacheScope 客户端的 host 与账号
+func (c *RunningHubClient) uploadCacheScope() string {
+	host := c.url
+	if idx := strings.Index(host, "://"); idx >= 0 {
+		host = host[idx+3:]
+	}
+	return UploadCacheScope(host, c.ApiKey)
+}
+
+// lookupUploadCacheByFile 计算文件哈希并查找缓存, 未配置缓存时返回空哈希
+func (c *RunningHubClient) lookupUploadCacheByFile(ctx context.Context, version string, filePath string) (md5Hex string, entry *UploadCacheEntry) {
+	if c.uploadCache == nil {
+		return "", nil
+	}
+	sum, err := hash.File(ctx, filePath, nil)
+	if err != nil {
+		return "", nil
+	}
+	md5Hex = sum.Md5Hex
+	entry, _ = c.uploadCache.Get(ctx, c.uploadCacheScope(), version, md5Hex)
+	return md5Hex, entry
+}
+
+// lookupUploadCacheByBytes 计算内容哈希并查找缓存, 未配置缓存时返回空哈希
+func (c *RunningHubClient) lookupUploadCacheByBytes(ctx context.Context, version string, data []byte) (md5Hex string, entry *UploadCacheEntry) {
+	if c.uploadCache == nil {
+		return "", nil
+	}
+	md5Hex = utility.GetBytesMd5Hex(data)
+	entry, _ = c.uploadCache.Get(ctx, c.uploadCacheScope(), version, md5Hex)
+	return md5Hex, entry
+}
+
+func (c *RunningHubClient) saveUploadCache(ctx context.Context, entry *UploadCacheEntry) {
+	if c.uploadCache == nil {
+		return
+	}
+	entry.Scope = c.uploadCacheScope()
+	// 缓存写入失败不影响上传结果
+	_ = c.uploadCache.Set(ctx, entry)
+}
@@ -0,0 +1,122 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUploadCacheScope(t *testing.T) {
+	a := UploadCacheScope("www.runninghub.cn", "key-a")
+	if a != UploadCacheScope("WWW.runninghub.cn", "key-a") {
+		t.Fatal("scope should ignore host case")
+	}
+	if a == UploadCacheScope("www.runninghub.cn", "key-b") || a == UploadCacheScope("www.runninghub.ai", "key-a") {
+		t.Fatal("different accounts share a scope")
+	}
+	if strings.Contains(a, "key-a") {
+		t.Fatal("scope contains the api key")
+	}
+}
+
+func TestUploadCacheSharedStore(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		uploads = make(map[string]int) // apiKey -> 上传次数
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/source.png" {
+			w.Write([]byte("png"))
+			return
+		}
+		reader, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var apiKey string
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			if part.FormName() == "apiKey" {
+				body, _ := io.ReadAll(part)
+				apiKey = string(body)
+			}
+		}
+		mu.Lock()
+		uploads[apiKey]++
+		mu.Unlock()
+		writeTestResponse(w, 0, "success", &UploadResourceRes{FileName: "api/" + apiKey + ".png", FileType: "input"})
+	}))
+	defer server.Close()
+	store := NewMemoryStore()
+	newClient := func(apiKey string) *RunningHubClient {
+		return NewClient(&RunningHubClientConfig{
+			UseHttpReq:  true,
+			Host:        strings.TrimPrefix(server.URL, "http://"),
+			ApiKey:      apiKey,
+			UploadCache: NewUploadCache(store, time.Hour),
+		})
+	}
+	clientA, clientB := newClient("key-a"), newClient("key-b")
+	ctx := context.Background()
+	src := &FileSource{UploadMode: UploadModeBytes, Bytes: []byte("same content"), FileName: "a.png"}
+	urlSrc := &FileSource{UploadMode: UploadModeUrl, Url: server.URL + "/source.png"}
+	steps := []struct {
+		client    *RunningHubClient
+		src       *FileSource
+		wantFile  string
+		wantCalls map[string]int
+	}{
+		{clientA, src, "api/key-a.png", map[string]int{"key-a": 1}},
+		{clientA, src, "api/key-a.png", map[string]int{"key-a": 1}},
+		{clientB, src, "api/key-b.png", map[string]int{"key-a": 1, "key-b": 1}},
+		{clientB, src, "api/key-b.png", map[string]int{"key-a": 1, "key-b": 1}},
+		// 链接上传不使用缓存
+		{clientA, urlSrc, "api/key-a.png", map[string]int{"key-a": 2, "key-b": 1}},
+		{clientA, urlSrc, "api/key-a.png", map[string]int{"key-a": 3, "key-b": 1}},
+	}
+	for i, step := range steps {
+		res, err := step.client.UploadFromSource(ctx, step.src, nil)
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if res.FileName != step.wantFile {
+			t.Fatalf("step %d: fileName = %s, want %s", i, res.FileName, step.wantFile)
+		}
+		mu.Lock()
+		for k, v := range step.wantCalls {
+			if uploads[k] != v {
+				t.Fatalf("step %d: uploads[%s] = %d, want %d", i, k, uploads[k], v)
+			}
+		}
+		mu.Unlock()
+	}
+}
+
+func TestUploadCacheExpire(t *testing.T) {
+	ctx := context.Background()
+	cache := NewUploadCache(nil, time.Hour)
+	scope := UploadCacheScope("host", "key")
+	err := cache.Set(ctx, &UploadCacheEntry{Scope: scope, Version: uploadCacheV1, Md5Hex: "abc", FileName: "old", UploadedAt: time.Now().Add(-2 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get(ctx, scope, uploadCacheV1, "abc"); ok {
+		t.Fatal("expired entry returned")
+	}
+	cache.Set(ctx, &UploadCacheEntry{Scope: scope, Version: uploadCacheV1, Md5Hex: "abc", FileName: "new"})
+	if entry, ok := cache.Get(ctx, scope, uploadCacheV1, "abc"); !ok || entry.FileName != "new" {
+		t.Fatalf("Get() = %+v, %v", entry, ok)
+	}
+	if _, ok := cache.Get(ctx, scope, uploadCacheV2, "abc"); ok {
+		t.Fatal("v1 entry returned for v2")
+	}
+}
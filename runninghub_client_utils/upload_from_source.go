@@ -0,0 +1,242 @@
+package runninghub_client_utils
+
+import (
+	"bytes"
+	"context"
+	"github.com/Friday-fighting/runninghub_tools/utility"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/util/gconv"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UploadFromSource 按 FileSource.UploadMode 上传内存、本地文件或链接资源, 通过 opts.UseV2 选择上传方式
+func (c *RunningHubClient) UploadFromSource(ctx context.Context, src *FileSource, opts *UploadSourceOption) (res *UploadSourceRes, err error) {
+	if src == nil {
+		return nil, gerror.New("file source cannot be nil")
+	}
+	if opts == nil {
+		opts = &UploadSourceOption{}
+	}
+	if opts.Preprocess != nil {
+		return c.uploadPreprocessedSource(ctx, src, opts)
+	}
+	version := uploadCacheV1
+	if opts.UseV2 {
+		version = uploadCacheV2
+	}
+	in := &UploadStreamInput{
+		FileName: src.FileName,
+		Progress: opts.Progress,
+	}
+	var (
+		md5Hex string
+		cached *UploadCacheEntry
+	)
+	switch src.UploadMode {
+	case UploadModeBytes:
+		if len(src.Bytes) == 0 {
+			return nil, gerror.New("bytes of file source cannot be empty")
+		}
+		if md5Hex, cached = c.lookupUploadCacheByBytes(ctx, version, src.Bytes); cached != nil {
+			return uploadCacheEntryToSourceRes(cached), nil
+		}
+		if in.FileName == "" {
+			in.FileName, in.ContentType = sniffFileName(src.Bytes)
+		}
+		in.Reader = bytes.NewReader(src.Bytes)
+		in.Size = int64(len(src.Bytes))
+	case UploadModeFile:
+		file, err := os.Open(src.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		stat, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if stat.IsDir() {
+			return nil, gerror.Newf("path(%s) does not point to a file", src.Path)
+		}
+		if md5Hex, cached = c.lookupUploadCacheByFile(ctx, version, src.Path); cached != nil {
+			return uploadCacheEntryToSourceRes(cached), nil
+		}
+		if in.FileName == "" {
+			in.FileName = filepath.Base(src.Path)
+		}
+		in.Reader = file
+		in.Size = stat.Size()
+	case UploadModeUrl:
+		// 链接内容需下载后才能计算哈希, 不查询也不写入上传缓存
+		body, meta, err := utility.OpenURL(ctx, src.Url)
+		if err != nil {
+			return nil, gerror.Wrap(err, "download file fail")
+		}
+		defer body.Close()
+		if in.FileName == "" {
+			in.FileName = meta.FileName
+		}
+		in.ContentType = meta.ContentType
+		in.Reader = body
+		in.Size = meta.ContentLength
+	default:
+		return nil, gerror.Newf("unsupported upload mode: %d", src.UploadMode)
+	}
+	res, err = c.uploadStream(ctx, in, opts.UseV2)
+	if err != nil {
+		return nil, err
+	}
+	c.saveUploadCache(ctx, &UploadCacheEntry{
+		Version:     version,
+		Md5Hex:      md5Hex,
+		FileName:    res.FileName,
+		FileType:    res.Type,
+		DownloadUrl: res.DownloadUrl,
+		Size:        gconv.String(res.Size),
+	})
+	return res, nil
+}
+
+func uploadCacheEntryToSourceRes(entry *UploadCacheEntry) *UploadSourceRes {
+	res := &UploadSourceRes{
+		FileName:    entry.FileName,
+		DownloadUrl: entry.DownloadUrl,
+		Size:        -1,
+		Type:        entry.FileType,
+	}
+	if entry.Size != "" {
+		res.Size = gconv.Int64(entry.Size)
+	}
+	return res
+}
+
+// sniffExts 常见资源类型优先使用的扩展名, 其余取 mime 包中的第一个
+var sniffExts = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"image/bmp":  ".bmp",
+	"video/mp4":  ".mp4",
+	"video/webm": ".webm",
+	"audio/mpeg": ".mp3",
+	"audio/wave": ".wav",
+	"text/plain": ".txt",
+}
+
+// sniffFileName 按内容识别类型, 返回带扩展名的文件名, 无法识别时为 file
+func sniffFileName(data []byte) (fileName string, contentType string) {
+	contentType = http.DetectContentType(data)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	ext, ok := sniffExts[mediaType]
+	if !ok && mediaType != "application/octet-stream" {
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	return "file" + ext, contentType
+}
+
+func (c *RunningHubClient) uploadStream(ctx context.Context, in *UploadStreamInput, useV2 bool) (res *UploadSourceRes, err error) {
+	if useV2 {
+		v2Res, err := c.UploadResourceV2FromStream(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		res = &UploadSourceRes{
+			FileName:    v2Res.FileName,
+			DownloadUrl: v2Res.DownloadUrl,
+			Size:        in.Size,
+			Type:        v2Res.Type,
+		}
+		if v2Res.Size != "" {
+			res.Size = gconv.Int64(v2Res.Size)
+		}
+		return res, nil
+	}
+	v1Res, err := c.UploadResourceFromStream(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadSourceRes{
+		FileName: v1Res.FileName,
+		Size:     in.Size,
+		Type:     v1Res.FileType,
+	}, nil
+}
+
+// uploadPreprocessedSource 读取资源并预处理图片后以内存方式上传
+func (c *RunningHubClient) uploadPreprocessedSource(ctx context.Context, src *FileSource, opts *UploadSourceOption) (res *UploadSourceRes, err error) {
+	r, fileName, err := openFileSource(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	processed, err := utility.PreprocessImage(ctx, r, opts.Preprocess)
+	if err != nil {
+		return nil, gerror.Wrap(err, "preprocess image fail")
+	}
+	if fileName == "" {
+		fileName = "image"
+	}
+	fileName = strings.TrimSuffix(fileName, filepath.Ext(fileName)) + processed.Ext
+	res, err = c.UploadFromSource(ctx, &FileSource{
+		UploadMode: UploadModeBytes,
+		Bytes:      processed.Data,
+		FileName:   fileName,
+	}, &UploadSourceOption{
+		UseV2:    opts.UseV2,
+		Progress: opts.Progress,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.Width = processed.Width
+	res.Height = processed.Height
+	return res, nil
+}
+
+// DimensionNodeInfos 生成填入宽高字段的 NodeInfo, 仅在预处理后有效
+func (r *UploadSourceRes) DimensionNodeInfos(nodeId string, widthFieldName string, heightFieldName string) []*NodeInfo {
+	return []*NodeInfo{
+		{NodeId: nodeId, FieldName: widthFieldName, FieldValue: gconv.String(r.Width)},
+		{NodeId: nodeId, FieldName: heightFieldName, FieldValue: gconv.String(r.Height)},
+	}
+}
+
+// openFileSource 打开内存、本地文件或链接资源, 返回内容与文件名
+func openFileSource(ctx context.Context, src *FileSource) (r io.ReadCloser, fileName string, err error) {
+	fileName = src.FileName
+	switch src.UploadMode {
+	case UploadModeBytes:
+		if len(src.Bytes) == 0 {
+			return nil, "", gerror.New("bytes of file source cannot be empty")
+		}
+		return io.NopCloser(bytes.NewReader(src.Bytes)), fileName, nil
+	case UploadModeFile:
+		file, err := os.Open(src.Path)
+		if err != nil {
+			return nil, "", err
+		}
+		if fileName == "" {
+			fileName = filepath.Base(src.Path)
+		}
+		return file, fileName, nil
+	case UploadModeUrl:
+		body, meta, err := utility.OpenURL(ctx, src.Url)
+		if err != nil {
+			return nil, "", gerror.Wrap(err, "download file fail")
+		}
+		if fileName == "" {
+			fileName = meta.FileName
+		}
+		return body, fileName, nil
+	default:
+		return nil, "", gerror.Newf("unsupported upload mode: %d", src.UploadMode)
+	}
+}
@@ -0,0 +1,141 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// uploadedFile 测试服务收到的上传文件
+type uploadedFile struct {
+	Name        string
+	ContentType string
+	Content     string
+	V2          bool
+}
+
+// fakeUploadServer 模拟 v1 与 v2 上传接口, 并在 /source/ 下提供链接资源
+type fakeUploadServer struct {
+	mu    sync.Mutex
+	files []uploadedFile
+}
+
+func (f *fakeUploadServer) uploads() []uploadedFile {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]uploadedFile(nil), f.files...)
+}
+
+func (f *fakeUploadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if name, ok := strings.CutPrefix(r.URL.Path, "/source/"); ok {
+		if name == "attachment" {
+			w.Header().Set("Content-Disposition", `attachment; filename="dir/named.png"`)
+		}
+		w.Header().Set("Content-Type", "image/png")
+		io.WriteString(w, "png from link")
+		return
+	}
+	if r.URL.Path != uploadResource {
+		http.NotFound(w, r)
+		return
+	}
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	content, _ := io.ReadAll(file)
+	// v2 使用 Authorization 头, v1 使用 apiKey 表单字段
+	v2 := r.Header.Get("Authorization") == "Bearer test-api-key"
+	if !v2 && r.FormValue("apiKey") != "test-api-key" {
+		writeTestResponse(w, 412, "TOKEN_INVALID", nil)
+		return
+	}
+	f.mu.Lock()
+	f.files = append(f.files, uploadedFile{Name: header.Filename, ContentType: header.Header.Get("Content-Type"), Content: string(content), V2: v2})
+	f.mu.Unlock()
+	if v2 {
+		writeTestResponse(w, 0, "success", &UploadResourceV2Res{Type: "image", DownloadUrl: "https://example.com/" + header.Filename, FileName: "api/" + header.Filename, Size: "1"})
+		return
+	}
+	writeTestResponse(w, 0, "success", &UploadResourceRes{FileName: "api/" + header.Filename, FileType: "input"})
+}
+
+// testPNG 返回 1x1 的 png 图片
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf strings.Builder
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	return []byte(buf.String())
+}
+
+func TestUploadFromSource(t *testing.T) {
+	pngData := testPNG(t)
+	path := filepath.Join(t.TempDir(), "local.jpg")
+	if err := os.WriteFile(path, []byte("jpg from file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		src         *FileSource
+		useV2       bool
+		want        uploadedFile
+		wantUrl     string
+		wantFailure bool
+	}{
+		{name: "bytes sniffed png", src: &FileSource{UploadMode: UploadModeBytes, Bytes: pngData}, want: uploadedFile{Name: "file.png", ContentType: "image/png", Content: string(pngData)}},
+		{name: "bytes sniffed text", src: &FileSource{UploadMode: UploadModeBytes, Bytes: []byte("hello")}, want: uploadedFile{Name: "file.txt", ContentType: "text/plain; charset=utf-8", Content: "hello"}},
+		{name: "bytes unknown type", src: &FileSource{UploadMode: UploadModeBytes, Bytes: []byte{0, 1, 2}}, want: uploadedFile{Name: "file", ContentType: "application/octet-stream", Content: "\x00\x01\x02"}},
+		{name: "bytes named", src: &FileSource{UploadMode: UploadModeBytes, Bytes: pngData, FileName: "a.webp"}, want: uploadedFile{Name: "a.webp", ContentType: "image/webp", Content: string(pngData)}},
+		{name: "bytes empty", src: &FileSource{UploadMode: UploadModeBytes}, wantFailure: true},
+		{name: "file", src: &FileSource{UploadMode: UploadModeFile, Path: path}, want: uploadedFile{Name: "local.jpg", ContentType: "image/jpeg", Content: "jpg from file"}},
+		{name: "file v2", src: &FileSource{UploadMode: UploadModeFile, Path: path, FileName: "renamed.jpg"}, useV2: true, want: uploadedFile{Name: "renamed.jpg", ContentType: "image/jpeg", Content: "jpg from file", V2: true}, wantUrl: "https://example.com/renamed.jpg"},
+		{name: "file missing", src: &FileSource{UploadMode: UploadModeFile, Path: path + ".missing"}, wantFailure: true},
+		{name: "file is dir", src: &FileSource{UploadMode: UploadModeFile, Path: filepath.Dir(path)}, wantFailure: true},
+		{name: "link", src: &FileSource{UploadMode: UploadModeUrl, Url: "/source/link.png"}, want: uploadedFile{Name: "link.png", ContentType: "image/png", Content: "png from link"}},
+		{name: "link content disposition", src: &FileSource{UploadMode: UploadModeUrl, Url: "/source/attachment"}, useV2: true, want: uploadedFile{Name: "named.png", ContentType: "image/png", Content: "png from link", V2: true}, wantUrl: "https://example.com/named.png"},
+		{name: "link not found", src: &FileSource{UploadMode: UploadModeUrl, Url: "/missing.png"}, wantFailure: true},
+		{name: "unknown mode", src: &FileSource{UploadMode: 9}, wantFailure: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeUploadServer{}
+			client := newTestClient(t, fake)
+			if tt.src.UploadMode == UploadModeUrl {
+				tt.src.Url = client.url + tt.src.Url
+			}
+			res, err := client.UploadFromSource(context.Background(), tt.src, &UploadSourceOption{UseV2: tt.useV2})
+			if tt.wantFailure {
+				if err == nil {
+					t.Fatalf("UploadFromSource() = %+v, want error", res)
+				}
+				if got := fake.uploads(); len(got) != 0 {
+					t.Fatalf("uploads = %+v, want none", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fake.uploads(); len(got) != 1 || got[0] != tt.want {
+				t.Fatalf("uploads = %+v, want %+v", got, tt.want)
+			}
+			if res.FileName != "api/"+tt.want.Name || res.DownloadUrl != tt.wantUrl {
+				t.Fatalf("UploadFromSource() = %+v", res)
+			}
+		})
+	}
+}
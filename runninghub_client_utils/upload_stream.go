@@ -0,0 +1,210 @@
+package runninghub_client_utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+)
+
+// UploadResourceFromReader 以流的方式上传资源, 不写临时文件, 不查询上传缓存
+func (c *RunningHubClient) UploadResourceFromReader(ctx context.Context, fileName string, r io.Reader) (res *UploadResourceRes, err error) {
+	return c.UploadResourceFromStream(ctx, &UploadStreamInput{
+		FileName: fileName,
+		Reader:   r,
+		Size:     -1,
+	})
+}
+
+// UploadResourceFromStream 以流的方式上传资源, 支持进度回调, 不查询上传缓存
+func (c *RunningHubClient) UploadResourceFromStream(ctx context.Context, in *UploadStreamInput) (res *UploadResourceRes, err error) {
+	if in == nil || in.Reader == nil {
+		return nil, gerror.New("reader cannot be nil")
+	}
+	url := fmt.Sprintf("%s%s", c.url, uploadResource)
+	fields := map[string]string{
+		"apiKey":   c.ApiKey,
+		"fileType": "input",
+	}
+	resp, err := c.doPostMultipartStream(ctx, url, nil, fields, in)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 0 || resp.Data == nil {
+		return nil, gerror.Newf("UploadResource fail, code: %d, msg: %s", resp.Code, resp.Msg)
+	}
+	if err := json.Unmarshal(resp.Data, &res); err != nil {
+		return nil, fmt.Errorf("decode success data fail: %w", err)
+	}
+	return res, nil
+}
+
+// UploadResourceV2FromStream 以流的方式通过 v2 方式上传资源, 支持进度回调, 不查询上传缓存
+func (c *RunningHubClient) UploadResourceV2FromStream(ctx context.Context, in *UploadStreamInput) (res *UploadResourceV2Res, err error) {
+	if in == nil || in.Reader == nil {
+		return nil, gerror.New("reader cannot be nil")
+	}
+	url := fmt.Sprintf("%s%s", c.url, uploadResource)
+	header := map[string]string{
+		"Authorization": "Bearer " + c.ApiKey,
+	}
+	resp, err := c.doPostMultipartStream(ctx, url, header, nil, in)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 0 || resp.Data == nil {
+		return nil, gerror.Newf("UploadResourceV2 fail, code: %d, msg: %s", resp.Code, resp.Msg)
+	}
+	if err := json.Unmarshal(resp.Data, &res); err != nil {
+		return nil, fmt.Errorf("decode success data fail: %w", err)
+	}
+	return res, nil
+}
+
+var errUploadStreamClosed = gerror.New("upload stream closed")
+
+// doPostMultipartStream 通过 io.Pipe 边读边写 multipart 请求体, 文件内容不在内存中缓存
+func (c *RunningHubClient) doPostMultipartStream(ctx context.Context, url string, header map[string]string, fields map[string]string, in *UploadStreamInput) (res *RunningHubResponse, err error) {
+	if in.FileName == "" {
+		in.FileName = "file"
+	}
+	if in.ContentType == "" {
+		in.ContentType = mime.TypeByExtension(filepath.Ext(in.FileName))
+	}
+	if in.ContentType == "" {
+		in.ContentType = "application/octet-stream"
+	}
+	var src io.Reader = in.Reader
+	if in.Progress != nil {
+		src = &progressReader{r: in.Reader, total: in.Size, progress: in.Progress}
+	}
+
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	contentLength := int64(-1)
+	if in.Size >= 0 {
+		contentLength, err = multipartLength(writer.Boundary(), fields, in)
+		if err != nil {
+			return nil, err
+		}
+		contentLength += in.Size
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pw.CloseWithError(writeMultipart(writer, fields, in, src))
+	}()
+	// 返回前关闭读端并等待写 multipart 的协程退出, 保证返回后不再读取调用方的 Reader
+	defer func() {
+		pr.CloseWithError(errUploadStreamClosed)
+		<-done
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, pr)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = contentLength
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	// 大文件上传耗时与文件大小相关, 默认不使用客户端的整体超时, 由 ctx 控制
+	httpClient := c.httpClient.Client
+	httpClient.Timeout = 0
+	if in.Timeout > 0 {
+		httpClient.Timeout = in.Timeout
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// 检查响应状态码
+	if resp.StatusCode != http.StatusOK {
+		return nil, gerror.Newf("unexpected status code: %d", resp.StatusCode)
+	}
+	return decodeRunningHubResponse(resp.Body)
+}
+
+// multipartLength 计算除文件内容外 multipart 请求体的长度
+func multipartLength(boundary string, fields map[string]string, in *UploadStreamInput) (int64, error) {
+	var counter bytes.Buffer
+	writer := multipart.NewWriter(&counter)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return 0, err
+	}
+	if err := writeMultipart(writer, fields, in, bytes.NewReader(nil)); err != nil {
+		return 0, err
+	}
+	return int64(counter.Len()), nil
+}
+
+func writeMultipart(writer *multipart.Writer, fields map[string]string, in *UploadStreamInput, r io.Reader) error {
+	for k, v := range fields {
+		if err := writer.WriteField(k, v); err != nil {
+			return err
+		}
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, escapeQuotes(in.FileName)))
+	h.Set("Content-Type", in.ContentType)
+	part, err := writer.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(part, r); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+type progressReader struct {
+	r        io.Reader
+	done     int64
+	total    int64
+	progress func(done, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.done += int64(n)
+		p.progress(p.done, p.total)
+	}
+	return n, err
+}
+
+func decodeRunningHubResponse(body io.Reader) (res *RunningHubResponse, err error) {
+	var response *RunningHubResponse
+	if err = json.NewDecoder(body).Decode(&response); err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return nil, gerror.New("empty response")
+	}
+	respData := response.Data
+	if len(respData) == 0 || bytes.Equal(respData, []byte("null")) {
+		response.Data = nil
+	}
+	errorMessages := response.ErrorMessages
+	if len(errorMessages) == 0 || bytes.Equal(errorMessages, []byte("null")) {
+		response.ErrorMessages = nil
+	}
+	return response, nil
+}
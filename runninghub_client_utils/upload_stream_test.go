@@ -0,0 +1,178 @@
+package runninghub_client_utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUploadResourceFromStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+		size     int64
+		wantType string
+	}{
+		{name: "known size", fileName: "a.png", content: "png data", size: 8, wantType: "image/png"},
+		{name: "unknown size", fileName: "b.mp4", content: strings.Repeat("v", 100000), size: -1, wantType: "video/mp4"},
+		{name: "quoted unicode name", fileName: `图 "1".jpg`, content: "jpg", size: 3, wantType: "image/jpeg"},
+		{name: "empty name", fileName: "", content: "", size: 0, wantType: "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Errorf("read body: %v", err)
+					return
+				}
+				// 长度已知时 Content-Length 必须与实际请求体一致
+				wantLength := int64(-1)
+				if tt.size >= 0 {
+					wantLength = int64(len(body))
+				}
+				if r.ContentLength != wantLength {
+					t.Errorf("Content-Length = %d, want %d", r.ContentLength, wantLength)
+				}
+				_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+				if err != nil {
+					t.Errorf("parse content type: %v", err)
+					return
+				}
+				form, err := multipart.NewReader(bytes.NewReader(body), params["boundary"]).ReadForm(1 << 20)
+				if err != nil {
+					t.Errorf("parse multipart: %v", err)
+					return
+				}
+				if got := form.Value["apiKey"]; len(got) != 1 || got[0] != "test-api-key" {
+					t.Errorf("apiKey = %v", got)
+				}
+				files := form.File["file"]
+				if len(files) != 1 {
+					t.Errorf("files = %d, want 1", len(files))
+					return
+				}
+				wantName := tt.fileName
+				if wantName == "" {
+					wantName = "file"
+				}
+				if files[0].Filename != wantName || files[0].Header.Get("Content-Type") != tt.wantType {
+					t.Errorf("file = %q %q, want %q %q", files[0].Filename, files[0].Header.Get("Content-Type"), wantName, tt.wantType)
+				}
+				f, _ := files[0].Open()
+				content, _ := io.ReadAll(f)
+				if string(content) != tt.content {
+					t.Errorf("content length = %d, want %d", len(content), len(tt.content))
+				}
+				writeTestResponse(w, 0, "success", map[string]string{"fileName": "api/" + wantName})
+			}))
+			var lastDone, lastTotal int64
+			res, err := client.UploadResourceFromStream(context.Background(), &UploadStreamInput{
+				FileName: tt.fileName,
+				Reader:   strings.NewReader(tt.content),
+				Size:     tt.size,
+				Progress: func(done, total int64) { lastDone, lastTotal = done, total },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.FileName == "" {
+				t.Fatal("empty fileName")
+			}
+			if len(tt.content) > 0 && (lastDone != int64(len(tt.content)) || lastTotal != tt.size) {
+				t.Fatalf("progress = %d/%d", lastDone, lastTotal)
+			}
+		})
+	}
+}
+
+// countingReader 无限输出数据, 记录读取次数; 读取不加锁, 返回后仍被读取时 -race 会报告
+type countingReader struct {
+	reads int
+	block <-chan struct{} // 非空时每次读取前等待
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	if r.block != nil {
+		<-r.block
+		return 0, errors.New("reader released")
+	}
+	r.reads++
+	for i := range b {
+		b[i] = 'x'
+	}
+	return len(b), nil
+}
+
+func TestUploadResourceFromStreamStopsReading(t *testing.T) {
+	t.Run("http error", func(t *testing.T) {
+		client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// 不读取请求体直接返回
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		r := &countingReader{}
+		if _, err := client.UploadResourceFromStream(context.Background(), &UploadStreamInput{Reader: r, Size: -1}); err == nil {
+			t.Fatal("expected error")
+		}
+		reads := r.reads
+		time.Sleep(50 * time.Millisecond)
+		if r.reads != reads {
+			t.Fatalf("reader used after return: %d -> %d", reads, r.reads)
+		}
+	})
+	t.Run("ctx cancel", func(t *testing.T) {
+		client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+		}))
+		ctx, cancel := context.WithCancel(context.Background())
+		block := make(chan struct{})
+		r := &countingReader{block: block}
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+			time.Sleep(50 * time.Millisecond)
+			close(block)
+		}()
+		start := time.Now()
+		_, err := client.UploadResourceFromStream(ctx, &UploadStreamInput{Reader: r, Size: -1})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v, want context.Canceled", err)
+		}
+		// 阻塞中的 Read 返回前不能返回
+		if time.Since(start) < 100*time.Millisecond {
+			t.Fatal("returned while reader was still in use")
+		}
+	})
+}
+
+func TestUploadResourceV2IgnoresClientTimeout(t *testing.T) {
+	slow := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		// 超过客户端 1s 的超时
+		time.Sleep(1200 * time.Millisecond)
+		writeTestResponse(w, 0, "success", map[string]string{"fileName": "api/a.png", "type": "image"})
+	}))
+	client := NewClient(&RunningHubClientConfig{UseHttpReq: true, Host: strings.TrimPrefix(slow.url, "http://"), ApiKey: "test-api-key", Timeout: 1})
+	path := filepath.Join(t.TempDir(), "a.png")
+	if err := os.WriteFile(path, []byte("png data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.UploadResourceV2(context.Background(), path); err != nil {
+		t.Fatalf("UploadResourceV2() = %v", err)
+	}
+	// 显式设置的超时仍然生效
+	_, err := client.UploadResourceV2FromStream(context.Background(), &UploadStreamInput{Reader: strings.NewReader("x"), Size: 1, Timeout: 100 * time.Millisecond})
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+}
@@ -0,0 +1,27 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"time"
+)
+
+// WaitTask 轮询任务状态直到任务结束或 ctx 取消, interval 小于等于 0 时默认 5 秒
+func (c *RunningHubClient) WaitTask(ctx context.Context, taskId string, interval time.Duration) (res *GetTaskStatusAndResultRes, err error) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		res, err = c.GetTaskStatusAndResult(ctx, taskId)
+		// 查询接口偶发错误时继续轮询
+		if err == nil && res.Status.IsTerminal() {
+			return res, nil
+		}
+		select {
+		case <-ctx.Done():
+			return res, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
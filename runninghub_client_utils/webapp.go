@@ -0,0 +1,79 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// GetWebAppInfo 获取 AI 应用的输入节点说明, 返回的 nodeInfoList 可修改 FieldValue 后用于 CreateWebAppTask
+func (c *RunningHubClient) GetWebAppInfo(ctx context.Context, webappId string) (res *GetWebAppInfoRes, err error) {
+	if webappId == "" {
+		return nil, gerror.New("webappId cannot be empty")
+	}
+	url := fmt.Sprintf("%s%s", c.url, getWebAppInfo)
+	resp, err := c.doGet(ctx, url, g.Map{
+		"apiKey":   c.ApiKey,
+		"webappId": webappId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 0 || resp.Data == nil {
+		return nil, &ApiError{Op: "GetWebAppInfo", Code: resp.Code, Msg: resp.Msg}
+	}
+	if err = json.Unmarshal(resp.Data, &res); err != nil {
+		return nil, fmt.Errorf("decode success data fail: %w", err)
+	}
+	return res, nil
+}
+
+// NodeInfos 将 AI 应用的输入节点转为 NodeInfo, 未修改的字段沿用默认值
+func (r *GetWebAppInfoRes) NodeInfos() []*NodeInfo {
+	res := make([]*NodeInfo, 0, len(r.NodeInfoList))
+	for _, v := range r.NodeInfoList {
+		res = append(res, &NodeInfo{
+			NodeId:     v.NodeId,
+			FieldName:  v.FieldName,
+			FieldValue: v.FieldValue,
+		})
+	}
+	return res
+}
+
+// CreateWebAppTask 通过 AI 应用创建任务, 状态查询、结果获取与取消与工作流任务相同
+func (c *RunningHubClient) CreateWebAppTask(ctx context.Context, payloadData *CreateWebAppTaskReq) (res *CreateTaskRes, err error) {
+	if payloadData == nil || payloadData.WebappId == "" || len(payloadData.NodeInfoList) == 0 {
+		return nil, gerror.New("create webapp task request params of WebappId and NodeInfoList must be not empty")
+	}
+	url := fmt.Sprintf("%s%s", c.url, createWebAppTask)
+	payloadData.ApiKey = c.ApiKey
+	reqBody, _ := json.Marshal(payloadData)
+
+	resp, err := c.doPost(ctx, url, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 0 || resp.Data == nil {
+		return nil, &ApiError{Op: "CreateWebAppTask", Code: resp.Code, Msg: resp.Msg}
+	}
+	res = &CreateTaskRes{
+		Code: resp.Code,
+		Msg:  resp.Msg,
+	}
+	if err := json.Unmarshal(resp.Data, &res); err != nil {
+		return nil, fmt.Errorf("decode success data fail: %w", err)
+	}
+	return res, nil
+}
+
+// StartWebAppTask 通过 AI 应用创建任务并返回绑定 ctx 的 TaskHandle
+func (c *RunningHubClient) StartWebAppTask(ctx context.Context, payloadData *CreateWebAppTaskReq, opt *TaskHandleOption) (handle *TaskHandle, err error) {
+	res, err := c.CreateWebAppTask(ctx, payloadData)
+	if err != nil {
+		return nil, err
+	}
+	return c.NewTaskHandle(ctx, res.TaskId, opt), nil
+}
@@ -0,0 +1,113 @@
+package runninghub_client_utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newWebAppFake() *fakeRunningHub {
+	fake := newFakeRunningHub()
+	fake.WebApps = map[string]*GetWebAppInfoRes{
+		"app-1": {
+			WebappName: "portrait",
+			NodeInfoList: []*WebAppNodeInfo{
+				{NodeId: "12", NodeName: "LoadImage", FieldName: "image", FieldValue: "default.png", FieldType: "IMAGE"},
+				{NodeId: "5", NodeName: "Prompt", FieldName: "text", FieldValue: "a cat", FieldType: "STRING"},
+			},
+		},
+	}
+	return fake
+}
+
+func TestGetWebAppInfo(t *testing.T) {
+	fake := newWebAppFake()
+	client := fake.client(t)
+	ctx := context.Background()
+	res, err := client.GetWebAppInfo(ctx, "app-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.webAppGets) != 1 || fake.webAppGets[0].Get("apiKey") != "test-api-key" || fake.webAppGets[0].Get("webappId") != "app-1" {
+		t.Fatalf("query = %v", fake.webAppGets)
+	}
+	if res.WebappName != "portrait" || len(res.NodeInfoList) != 2 || res.NodeInfoList[0].FieldType != "IMAGE" {
+		t.Fatalf("GetWebAppInfo() = %+v", res)
+	}
+
+	// NodeInfos 只保留提交所需的字段, 沿用默认值
+	nodes := res.NodeInfos()
+	if len(nodes) != 2 || *nodes[0] != (NodeInfo{NodeId: "12", FieldName: "image", FieldValue: "default.png"}) || *nodes[1] != (NodeInfo{NodeId: "5", FieldName: "text", FieldValue: "a cat"}) {
+		t.Fatalf("NodeInfos() = %+v %+v", nodes[0], nodes[1])
+	}
+	if got := (&GetWebAppInfoRes{}).NodeInfos(); got == nil || len(got) != 0 {
+		t.Fatalf("empty NodeInfos() = %v", got)
+	}
+
+	_, err = client.GetWebAppInfo(ctx, "missing")
+	var apiErr *ApiError
+	if !errors.As(err, &apiErr) || apiErr.Op != "GetWebAppInfo" || apiErr.Code != 901 || apiErr.Msg != "WEBAPP_NOT_EXISTS" {
+		t.Fatalf("GetWebAppInfo() err = %v, want *ApiError 901", err)
+	}
+	if _, err = client.GetWebAppInfo(ctx, ""); err == nil || len(fake.webAppGets) != 2 {
+		t.Fatalf("empty webappId err = %v, requests = %d", err, len(fake.webAppGets))
+	}
+}
+
+func TestCreateWebAppTask(t *testing.T) {
+	fake := newWebAppFake()
+	client := fake.client(t)
+	ctx := context.Background()
+	info, err := client.GetWebAppInfo(ctx, "app-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := info.NodeInfos()
+	nodes[1].FieldValue = "a dog"
+	req := &CreateWebAppTaskReq{WebappId: "app-1", NodeInfoList: nodes, InstanceType: "plus"}
+	res, err := client.CreateWebAppTask(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TaskId != "task-1" || res.TaskStatus != string(TaskStatusQueued) {
+		t.Fatalf("CreateWebAppTask() = %+v", res)
+	}
+	if len(fake.webAppReqs) != 1 {
+		t.Fatalf("requests = %d", len(fake.webAppReqs))
+	}
+	// apiKey 由客户端填入
+	got := fake.webAppReqs[0]
+	if got.ApiKey != "test-api-key" || got.WebappId != "app-1" || got.InstanceType != "plus" || got.WebhookUrl != "" ||
+		len(got.NodeInfoList) != 2 || got.NodeInfoList[1].FieldValue != "a dog" {
+		t.Fatalf("request = %+v", got)
+	}
+
+	// 创建的任务与工作流任务一样查询与等待
+	fake.FinishAt = 1
+	h, err := client.StartWebAppTask(ctx, &CreateWebAppTaskReq{WebappId: "app-1", NodeInfoList: nodes}, &TaskHandleOption{PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final, err := h.Wait(); err != nil || final.Status != TaskStatusSuccess {
+		t.Fatalf("Wait() = %+v, %v", final, err)
+	}
+
+	_, err = client.CreateWebAppTask(ctx, &CreateWebAppTaskReq{WebappId: "missing", NodeInfoList: nodes})
+	var apiErr *ApiError
+	if !errors.As(err, &apiErr) || apiErr.Op != "CreateWebAppTask" || apiErr.Code != 901 {
+		t.Fatalf("CreateWebAppTask() err = %v, want *ApiError 901", err)
+	}
+	if code, ok := ApiErrorCode(err); !ok || code != 901 {
+		t.Fatalf("ApiErrorCode() = %d, %v", code, ok)
+	}
+
+	for _, v := range []*CreateWebAppTaskReq{nil, {NodeInfoList: nodes}, {WebappId: "app-1"}} {
+		if _, err = client.CreateWebAppTask(ctx, v); err == nil {
+			t.Fatalf("CreateWebAppTask(%+v) should fail", v)
+		}
+	}
+	if len(fake.webAppReqs) != 3 {
+		t.Fatalf("invalid requests were sent, requests = %d", len(fake.webAppReqs))
+	}
+}
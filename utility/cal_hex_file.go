@@ -19,7 +19,7 @@ func GetLocalFileMd5Hex(filepath string) (md5Hex string, err error) {
 
 	// 流式拷贝到 hash
 	if _, err := io.Copy(hash, file); err != nil {
-		panic(err)
+		return "", err
 	}
 
 	// 计算结果
@@ -29,3 +29,8 @@ func GetLocalFileMd5Hex(filepath string) (md5Hex string, err error) {
 	md5Hex = hex.EncodeToString(md5Bytes)
 	return md5Hex, nil
 }
+
+func GetBytesMd5Hex(data []byte) string {
+	md5Bytes := md5.Sum(data)
+	return hex.EncodeToString(md5Bytes[:])
+}
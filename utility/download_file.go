@@ -1,29 +1,310 @@
 package utility
 
 import (
+	"context"
 	"crypto/rand"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/Friday-fighting/runninghub_tools/utility/hash"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// DownloadOption 下载参数
+type DownloadOption struct {
+	Timeout          time.Duration           // 单次请求超时, 0 表示只受 ctx 控制
+	MaxRetries       int                     // 失败重试次数, 默认 3, 小于 0 不重试
+	RetryInterval    time.Duration           // 首次重试间隔, 之后指数退避, 默认 1s
+	MaxRetryInterval time.Duration           // 最大重试间隔, 默认 30s
+	Md5Hex           string                  // 非空时下载完成后校验 MD5
+	Sha256Hex        string                  // 非空时下载完成后校验 SHA-256
+	Progress         func(done, total int64) // 进度回调, total 未知时为 -1
+}
+
+// retryableError 可以重试的下载错误
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
 func DownloadFromUrl(rawURL string, dir string) (string, error) {
-	return DownloadFromUrlWithTimeOut(rawURL, dir, 60)
+	return DownloadFromUrlWithTimeOut(rawURL, dir, 60*time.Second)
 }
 
+// DownloadFromUrlWithTimeOut 下载文件到目录, 文件名为 时间戳_随机串.扩展名; timeOut 小于等于 0 时默认 60s
 func DownloadFromUrlWithTimeOut(rawURL string, dir string, timeOut time.Duration) (string, error) {
 	if dir == "" {
 		dir = filepath.Join("temp", "cacheDownload")
-		err := os.MkdirAll(dir, os.ModePerm)
+	}
+	localPath, err := randomLocalPath(rawURL, dir)
+	if err != nil {
+		return "", err
+	}
+	_, err = DownloadToFile(context.Background(), rawURL, localPath, timeOut)
+	if err != nil {
+		return "", err
+	}
+	return localPath, nil
+}
+
+// DownloadToFile 下载文件到指定路径, 返回写入的字节数; timeOut 小于等于 0 时默认 60s
+func DownloadToFile(ctx context.Context, rawURL string, localPath string, timeOut time.Duration) (size int64, err error) {
+	if timeOut <= 0 {
+		timeOut = 60 * time.Second
+	}
+	return DownloadFileWithOption(ctx, rawURL, localPath, &DownloadOption{
+		Timeout: timeOut,
+	})
+}
+
+// DownloadFileWithOption 下载文件到指定路径, 先写入 .part 文件, 支持 Range 断点续传与失败重试, 完成后原子重命名;
+// .part.json 记录 .part 文件对应的链接与 ETag, 链接不同或服务端文件已变化时丢弃 .part 重新下载
+func DownloadFileWithOption(ctx context.Context, rawURL string, localPath string, opt *DownloadOption) (size int64, err error) {
+	if opt == nil {
+		opt = &DownloadOption{}
+	}
+	if opt.MaxRetries == 0 {
+		opt.MaxRetries = 3
+	}
+	if opt.RetryInterval <= 0 {
+		opt.RetryInterval = time.Second
+	}
+	if opt.MaxRetryInterval <= 0 {
+		opt.MaxRetryInterval = 30 * time.Second
+	}
+	if _, err = url.Parse(rawURL); err != nil {
+		return 0, fmt.Errorf("invalid url: %w", err)
+	}
+	if dir := filepath.Dir(localPath); dir != "" {
+		if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+			return 0, err
+		}
+	}
+	partPath := localPath + ".part"
+	interval := opt.RetryInterval
+	for attempt := 0; ; attempt++ {
+		size, err = downloadPart(ctx, rawURL, partPath, opt)
+		if err == nil {
+			break
+		}
+		var retryErr *retryableError
+		if !errors.As(err, &retryErr) || attempt >= opt.MaxRetries {
+			return 0, fmt.Errorf("download failed: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("download failed: %w", ctx.Err())
+		case <-time.After(interval):
+		}
+		interval *= 2
+		if interval > opt.MaxRetryInterval {
+			interval = opt.MaxRetryInterval
+		}
+	}
+	if opt.Md5Hex != "" || opt.Sha256Hex != "" {
+		sum, err := hash.File(ctx, partPath, nil)
+		if err != nil {
+			return 0, err
+		}
+		if opt.Md5Hex != "" && !strings.EqualFold(sum.Md5Hex, opt.Md5Hex) {
+			removePart(partPath)
+			return 0, fmt.Errorf("md5 mismatch, expect: %s, got: %s", opt.Md5Hex, sum.Md5Hex)
+		}
+		if opt.Sha256Hex != "" && !strings.EqualFold(sum.Sha256Hex, opt.Sha256Hex) {
+			removePart(partPath)
+			return 0, fmt.Errorf("sha256 mismatch, expect: %s, got: %s", opt.Sha256Hex, sum.Sha256Hex)
+		}
+	}
+	if err = os.Rename(partPath, localPath); err != nil {
+		return 0, err
+	}
+	os.Remove(partMetaPath(partPath))
+	return size, nil
+}
+
+// partMeta .part 文件的来源, 用于判断能否续传
+type partMeta struct {
+	Url          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+func partMetaPath(partPath string) string {
+	return partPath + ".json"
+}
+
+func readPartMeta(partPath string) (*partMeta, bool) {
+	body, err := os.ReadFile(partMetaPath(partPath))
+	if err != nil {
+		return nil, false
+	}
+	meta := &partMeta{}
+	if err = json.Unmarshal(body, meta); err != nil {
+		return nil, false
+	}
+	return meta, true
+}
+
+func writePartMeta(partPath string, meta *partMeta) error {
+	body, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(partMetaPath(partPath), body, os.ModePerm)
+}
+
+// removePart 删除 .part 文件与其来源记录
+func removePart(partPath string) {
+	os.Remove(partPath)
+	os.Remove(partMetaPath(partPath))
+}
+
+// validator 返回 If-Range 使用的校验值, 优先使用强 ETag
+func (m *partMeta) validator() string {
+	if m.ETag != "" && !strings.HasPrefix(m.ETag, "W/") {
+		return m.ETag
+	}
+	return m.LastModified
+}
+
+// downloadPart 从 .part 文件已有长度处继续下载, 返回文件总长度
+func downloadPart(ctx context.Context, rawURL string, partPath string, opt *DownloadOption) (int64, error) {
+	var offset int64
+	meta, ok := readPartMeta(partPath)
+	if stat, err := os.Stat(partPath); err == nil {
+		if ok && meta.Url == rawURL {
+			offset = stat.Size()
+		} else {
+			// 来源未知或来自其他链接, 不能续传
+			removePart(partPath)
+		}
+	}
+	reqCtx := ctx
+	if opt.Timeout > 0 {
+		var cancel context.CancelFunc
+		reqCtx, cancel = context.WithTimeout(ctx, opt.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		// 服务端文件已变化时返回 200 与完整内容
+		if v := meta.validator(); v != "" {
+			req.Header.Set("If-Range", v)
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, &retryableError{err: err}
+	}
+	defer resp.Body.Close()
+
+	flag := os.O_CREATE | os.O_WRONLY
+	total := int64(-1)
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if offset == 0 || meta.ETag != "" && resp.Header.Get("ETag") != "" && resp.Header.Get("ETag") != meta.ETag {
+			removePart(partPath)
+			return 0, &retryableError{err: fmt.Errorf("unexpected partial content")}
+		}
+		flag |= os.O_APPEND
+		total = parseContentRangeTotal(resp.Header.Get("Content-Range"))
+	case http.StatusOK:
+		// 服务端不支持 Range 或文件已变化, 从头下载
+		offset = 0
+		flag |= os.O_TRUNC
+		total = resp.ContentLength
+	case http.StatusRequestedRangeNotSatisfiable:
+		// 本地 .part 文件已损坏或超出长度, 丢弃后重新下载
+		removePart(partPath)
+		return 0, &retryableError{err: fmt.Errorf("bad status: %s", resp.Status)}
+	default:
+		err = fmt.Errorf("bad status: %s", resp.Status)
+		if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+			return 0, &retryableError{err: err}
+		}
+		return 0, err
+	}
+
+	if flag&os.O_TRUNC != 0 {
+		err = writePartMeta(partPath, &partMeta{
+			Url:          rawURL,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		})
 		if err != nil {
-			return "", err
+			return 0, err
 		}
 	}
+	out, err := os.OpenFile(partPath, flag, os.ModePerm)
+	if err != nil {
+		return 0, err
+	}
+	var dst io.Writer = out
+	if opt.Progress != nil {
+		dst = &progressWriter{w: out, done: offset, total: total, progress: opt.Progress}
+	}
+	n, err := io.Copy(dst, resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	size := offset + n
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, &retryableError{err: err}
+	}
+	if total >= 0 && size != total {
+		return 0, &retryableError{err: fmt.Errorf("size mismatch, expect: %d, got: %d", total, size)}
+	}
+	return size, nil
+}
+
+// parseContentRangeTotal 解析 Content-Range: bytes 100-199/200 中的总长度, 未知时返回 -1
+func parseContentRangeTotal(contentRange string) int64 {
+	idx := strings.LastIndex(contentRange, "/")
+	if idx < 0 {
+		return -1
+	}
+	total, err := strconv.ParseInt(contentRange[idx+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return total
+}
+
+type progressWriter struct {
+	w        io.Writer
+	done     int64
+	total    int64
+	progress func(done, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	p.progress(p.done, p.total)
+	return n, err
+}
+
+// randomLocalPath 生成 dir/时间戳_随机串.扩展名
+func randomLocalPath(rawURL string, dir string) (string, error) {
 	// 1. 解析 URL 并提取扩展名
 	u, err := url.Parse(rawURL)
 	if err != nil {
@@ -52,34 +333,5 @@ func DownloadFromUrlWithTimeOut(rawURL string, dir string, timeOut time.Duration
 		string(letter),
 		ext,
 	)
-	localPath := filepath.Join(dir, name)
-
-	// 4. 创建文件
-	out, err := os.Create(localPath)
-	if err != nil {
-		return "", err
-	}
-	defer out.Close()
-	if timeOut <= 0 {
-		timeOut = 60
-	}
-	// 5. 下载
-	client := &http.Client{
-		Timeout: timeOut * time.Second,
-	}
-	resp, err := client.Get(rawURL)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("bad status: %s", resp.Status)
-	}
-
-	// 6. 写盘
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("download failed: %w", err)
-	}
-	return localPath, err
+	return filepath.Join(dir, name), nil
 }
@@ -0,0 +1,187 @@
+package utility
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rangeServer 支持 Range 与 If-Range 的测试服务, 记录每次请求的 Range 头
+type rangeServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	content  []byte
+	etag     string
+	ranges   []string
+	failures int // 之后的请求返回 500 的次数
+}
+
+func newRangeServer(t *testing.T, content []byte, etag string) *rangeServer {
+	s := &rangeServer{content: content, etag: etag}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+		content, etag := s.content, s.etag
+		fail := s.failures > 0
+		if fail {
+			s.failures--
+		}
+		s.mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *rangeServer) requestRanges() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ranges...)
+}
+
+func TestDownloadFileWithOption(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	sum := md5.Sum(content)
+	md5Hex := hex.EncodeToString(sum[:])
+	tests := []struct {
+		name       string
+		path       string // 请求路径
+		part       []byte // 预先写入的 .part 内容
+		partMeta   *partMeta
+		etag       string
+		failures   int
+		md5Hex     string
+		wantRange  string // 第一次请求的 Range 头
+		wantErr    bool
+		wantRanges int
+	}{
+		{name: "fresh", path: "/a", etag: `"v1"`, wantRanges: 1},
+		{name: "resume", path: "/a", etag: `"v1"`, part: content[:4000], partMeta: &partMeta{ETag: `"v1"`}, wantRange: "bytes=4000-", wantRanges: 1},
+		{name: "part without meta is discarded", path: "/a", etag: `"v1"`, part: []byte("garbage"), wantRanges: 1},
+		{name: "part from another url is discarded", path: "/a", etag: `"v1"`, part: []byte("garbage"), partMeta: &partMeta{Url: "other"}, wantRanges: 1},
+		{name: "changed etag restarts", path: "/a", etag: `"v2"`, part: []byte("garbage"), partMeta: &partMeta{ETag: `"v1"`}, wantRange: "bytes=7-", wantRanges: 1},
+		{name: "retry server error", path: "/a", etag: `"v1"`, failures: 2, wantRanges: 3},
+		{name: "md5 ok", path: "/a", etag: `"v1"`, md5Hex: md5Hex, wantRanges: 1},
+		{name: "md5 mismatch", path: "/a", etag: `"v1"`, md5Hex: strings.Repeat("0", 32), wantErr: true, wantRanges: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRangeServer(t, content, tt.etag)
+			server.failures = tt.failures
+			rawURL := server.URL + tt.path
+			localPath := filepath.Join(t.TempDir(), "out", "file.bin")
+			partPath := localPath + ".part"
+			if tt.part != nil {
+				os.MkdirAll(filepath.Dir(localPath), os.ModePerm)
+				if err := os.WriteFile(partPath, tt.part, os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+				if tt.partMeta != nil {
+					meta := *tt.partMeta
+					if meta.Url == "" {
+						meta.Url = rawURL
+					}
+					if err := writePartMeta(partPath, &meta); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			var lastDone, lastTotal int64
+			size, err := DownloadFileWithOption(context.Background(), rawURL, localPath, &DownloadOption{
+				RetryInterval: time.Millisecond,
+				Md5Hex:        tt.md5Hex,
+				Progress:      func(done, total int64) { lastDone, lastTotal = done, total },
+			})
+			ranges := server.requestRanges()
+			if len(ranges) != tt.wantRanges {
+				t.Fatalf("requests = %d, want %d", len(ranges), tt.wantRanges)
+			}
+			if ranges[0] != tt.wantRange {
+				t.Fatalf("first Range = %q, want %q", ranges[0], tt.wantRange)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				if _, err = os.Stat(localPath); err == nil {
+					t.Fatal("file exists after failed download")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := os.ReadFile(localPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size != int64(len(content)) || !bytes.Equal(body, content) {
+				t.Fatalf("downloaded %d bytes, content match: %v", size, bytes.Equal(body, content))
+			}
+			if lastDone != size || lastTotal != size {
+				t.Fatalf("progress = %d/%d, want %d/%d", lastDone, lastTotal, size, size)
+			}
+			for _, v := range []string{partPath, partMetaPath(partPath)} {
+				if _, err = os.Stat(v); err == nil {
+					t.Fatalf("%s left behind", v)
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadFileWithOptionNotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	localPath := filepath.Join(t.TempDir(), "file.bin")
+	if _, err := DownloadFileWithOption(context.Background(), server.URL, localPath, &DownloadOption{RetryInterval: time.Millisecond}); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := os.Stat(localPath); err == nil {
+		t.Fatal("file exists after failed download")
+	}
+}
+
+func TestDownloadFromUrlWithTimeOut(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("data"))
+	}))
+	t.Cleanup(server.Close)
+	dir := t.TempDir()
+	// timeOut 按 time.Duration 处理, 不再乘以 time.Second
+	for _, timeOut := range []time.Duration{500 * time.Millisecond, 5 * time.Second, 0} {
+		localPath, err := DownloadFromUrlWithTimeOut(server.URL+"/a.png", dir, timeOut)
+		if err != nil || filepath.Ext(localPath) != ".png" {
+			t.Errorf("DownloadFromUrlWithTimeOut(%s) = %s, %v", timeOut, localPath, err)
+		}
+	}
+}
+
+func TestParseContentRangeTotal(t *testing.T) {
+	tests := map[string]int64{
+		"bytes 100-199/200": 200,
+		"bytes 0-0/1":       1,
+		"bytes 0-99/*":      -1,
+		"":                  -1,
+	}
+	for in, want := range tests {
+		if got := parseContentRangeTotal(in); got != want {
+			t.Errorf("parseContentRangeTotal(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
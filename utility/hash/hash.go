@@ -0,0 +1,202 @@
+package hash
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Option 哈希计算参数
+type Option struct {
+	Progress func(done, total int64) // 进度回调, total 未知时为 -1
+	Size     int64                   // Reader 的内容长度, 仅用于进度回调, 未知时为 0 或 -1
+}
+
+// Result 单次读取同时得到的 MD5, SHA-256 与 CRC32 结果
+type Result struct {
+	Md5Hex    string `json:"md5Hex"`
+	Sha256Hex string `json:"sha256Hex"`
+	Crc32Hex  string `json:"crc32Hex"` // IEEE 多项式, 8 位小写十六进制
+	Size      int64  `json:"size"`
+}
+
+// Reader 流式计算 r 的 MD5, SHA-256 与 CRC32, ctx 取消时中断
+func Reader(ctx context.Context, r io.Reader, opt *Option) (res *Result, err error) {
+	if opt == nil {
+		opt = &Option{}
+	}
+	total := opt.Size
+	if total <= 0 {
+		total = -1
+	}
+	md5Hash := md5.New()
+	sha256Hash := sha256.New()
+	crc32Hash := crc32.NewIEEE()
+	w := io.MultiWriter(md5Hash, sha256Hash, crc32Hash)
+	src := &ctxReader{ctx: ctx, r: r, total: total, progress: opt.Progress}
+	size, err := io.Copy(w, src)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Md5Hex:    hex.EncodeToString(md5Hash.Sum(nil)),
+		Sha256Hex: hex.EncodeToString(sha256Hash.Sum(nil)),
+		Crc32Hex:  hex.EncodeToString(crc32Hash.Sum(nil)),
+		Size:      size,
+	}, nil
+}
+
+// File 流式计算文件的 MD5, SHA-256 与 CRC32
+func File(ctx context.Context, filePath string, opt *Option) (res *Result, err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	fileOpt := &Option{Size: stat.Size()}
+	if opt != nil {
+		fileOpt.Progress = opt.Progress
+	}
+	return Reader(ctx, file, fileOpt)
+}
+
+// DirOption 目录哈希参数
+type DirOption struct {
+	Concurrency int                                   // 并行计算文件数, 默认 4
+	Filter      func(relPath string) bool             // 返回 false 的文件不计算, 为空时计算全部文件
+	Progress    func(relPath string, done, total int) // 每完成一个文件回调一次
+}
+
+// ManifestEntry 目录清单中的单个文件
+type ManifestEntry struct {
+	Path string `json:"path"` // 相对目录的路径, 使用 / 分隔
+	Result
+}
+
+// Manifest 目录哈希清单
+type Manifest struct {
+	Dir       string           `json:"dir"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Files     []*ManifestEntry `json:"files"` // 按 Path 升序
+}
+
+// Dir 以有限并发计算目录下所有文件的哈希, 生成清单
+func Dir(ctx context.Context, dir string, opt *DirOption) (res *Manifest, err error) {
+	if opt == nil {
+		opt = &DirOption{}
+	}
+	if opt.Concurrency <= 0 {
+		opt.Concurrency = 4
+	}
+	var relPaths []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if opt.Filter != nil && !opt.Filter(relPath) {
+			return nil
+		}
+		relPaths = append(relPaths, relPath)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(relPaths)
+
+	res = &Manifest{
+		Dir:       dir,
+		CreatedAt: time.Now(),
+		Files:     make([]*ManifestEntry, len(relPaths)),
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		done     int
+		firstErr error
+		sem      = make(chan struct{}, opt.Concurrency)
+	)
+	for i, relPath := range relPaths {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, relPath string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result, err := File(ctx, filepath.Join(dir, filepath.FromSlash(relPath)), nil)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("hash %s fail: %w", relPath, err)
+					cancel()
+				}
+				return
+			}
+			res.Files[i] = &ManifestEntry{Path: relPath, Result: *result}
+			done++
+			if opt.Progress != nil {
+				opt.Progress(relPath, done, len(relPaths))
+			}
+		}(i, relPath)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+type ctxReader struct {
+	ctx      context.Context
+	r        io.Reader
+	done     int64
+	total    int64
+	progress func(done, total int64)
+}
+
+func (c *ctxReader) Read(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := c.r.Read(b)
+	if n > 0 {
+		c.done += int64(n)
+		if c.progress != nil {
+			c.progress(c.done, c.total)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,179 @@
+package hash
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var testVectors = []struct {
+	name   string
+	data   string
+	md5    string
+	sha256 string
+	crc32  string
+}{
+	{"empty", "", "d41d8cd98f00b204e9800998ecf8427e", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", "00000000"},
+	{"abc", "abc", "900150983cd24fb0d6963f7d28e17f72", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", "352441c2"},
+	{"fox", "The quick brown fox jumps over the lazy dog", "9e107d9d372bb6826bd81d3542a419d6", "d7a8fbb307d7809469ca9abcb0082e4f8d5651e46d3cdb762d02d0bf37c9e592", "414fa339"},
+}
+
+func checkResult(t *testing.T, name string, res *Result, md5, sha256, crc32 string, size int) {
+	t.Helper()
+	if res.Md5Hex != md5 || res.Sha256Hex != sha256 || res.Crc32Hex != crc32 || res.Size != int64(size) {
+		t.Errorf("%s: result = %+v", name, res)
+	}
+}
+
+func TestReaderAndFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, tt := range testVectors {
+		res, err := Reader(context.Background(), strings.NewReader(tt.data), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkResult(t, "Reader "+tt.name, res, tt.md5, tt.sha256, tt.crc32, len(tt.data))
+
+		path := filepath.Join(dir, tt.name)
+		if err = os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if res, err = File(context.Background(), path, nil); err != nil {
+			t.Fatal(err)
+		}
+		checkResult(t, "File "+tt.name, res, tt.md5, tt.sha256, tt.crc32, len(tt.data))
+	}
+	if _, err := File(context.Background(), filepath.Join(dir, "missing"), nil); err == nil {
+		t.Fatal("File() of missing file should fail")
+	}
+}
+
+func TestProgress(t *testing.T) {
+	data := strings.Repeat("x", 100000)
+	path := filepath.Join(t.TempDir(), "a.bin")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		run       func(opt *Option) (*Result, error)
+		wantTotal int64
+	}{
+		{name: "reader without size", run: func(opt *Option) (*Result, error) {
+			return Reader(context.Background(), strings.NewReader(data), opt)
+		}, wantTotal: -1},
+		{name: "reader with size", run: func(opt *Option) (*Result, error) {
+			opt.Size = int64(len(data))
+			return Reader(context.Background(), strings.NewReader(data), opt)
+		}, wantTotal: int64(len(data))},
+		// File 使用文件大小作为 total
+		{name: "file", run: func(opt *Option) (*Result, error) {
+			return File(context.Background(), path, opt)
+		}, wantTotal: int64(len(data))},
+	}
+	for _, tt := range tests {
+		var calls int
+		var lastDone, lastTotal int64
+		_, err := tt.run(&Option{Progress: func(done, total int64) {
+			if done < lastDone {
+				t.Errorf("%s: progress went back %d -> %d", tt.name, lastDone, done)
+			}
+			calls++
+			lastDone, lastTotal = done, total
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if calls == 0 || lastDone != int64(len(data)) || lastTotal != tt.wantTotal {
+			t.Errorf("%s: calls = %d, last = %d/%d", tt.name, calls, lastDone, lastTotal)
+		}
+	}
+}
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{"a.txt": "abc", "sub/b.txt": "The quick brown fox jumps over the lazy dog", "sub/c.log": "", "d.txt": ""}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var (
+		mu       sync.Mutex
+		progress []int
+	)
+	manifest, err := Dir(context.Background(), dir, &DirOption{
+		Concurrency: 2,
+		Filter:      func(relPath string) bool { return strings.HasSuffix(relPath, ".txt") },
+		Progress: func(relPath string, done, total int) {
+			mu.Lock()
+			defer mu.Unlock()
+			if total != 3 {
+				t.Errorf("progress total = %d", total)
+			}
+			progress = append(progress, done)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, v := range manifest.Files {
+		paths = append(paths, v.Path)
+	}
+	// 按相对路径排序, 使用 / 分隔, 过滤后的文件不在清单中
+	if strings.Join(paths, ",") != "a.txt,d.txt,sub/b.txt" {
+		t.Fatalf("paths = %v", paths)
+	}
+	checkResult(t, "a.txt", &manifest.Files[0].Result, testVectors[1].md5, testVectors[1].sha256, testVectors[1].crc32, 3)
+	checkResult(t, "d.txt", &manifest.Files[1].Result, testVectors[0].md5, testVectors[0].sha256, testVectors[0].crc32, 0)
+	checkResult(t, "sub/b.txt", &manifest.Files[2].Result, testVectors[2].md5, testVectors[2].sha256, testVectors[2].crc32, 43)
+	if len(progress) != 3 || progress[2] != 3 {
+		t.Fatalf("progress = %v", progress)
+	}
+}
+
+// cancelReader 第一次读取后取消 ctx
+type cancelReader struct {
+	cancel context.CancelFunc
+	reads  int
+}
+
+func (r *cancelReader) Read(b []byte) (int, error) {
+	r.reads++
+	r.cancel()
+	for i := range b {
+		b[i] = 'x'
+	}
+	return len(b), nil
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &cancelReader{cancel: cancel}
+	if _, err := Reader(ctx, r, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Reader() err = %v, want context.Canceled", err)
+	}
+	if r.reads != 1 {
+		t.Fatalf("reads = %d, want 1", r.reads)
+	}
+
+	dir := t.TempDir()
+	for i := 0; i < 10; i++ {
+		os.WriteFile(filepath.Join(dir, string(rune('a'+i))), []byte("data"), 0o644)
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Dir(cancelled, dir, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Dir() err = %v, want context.Canceled", err)
+	}
+	if _, err := Dir(context.Background(), filepath.Join(dir, "missing"), nil); err == nil {
+		t.Fatal("Dir() of missing dir should fail")
+	}
+}
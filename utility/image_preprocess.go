@@ -0,0 +1,268 @@
+package utility
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"os"
+)
+
+const (
+	ImageFormatPNG  = "png"
+	ImageFormatJPEG = "jpeg"
+)
+
+// ImagePreprocessOption 图片预处理参数, 零值表示不做对应处理
+type ImagePreprocessOption struct {
+	MaxDimension  int     // 最长边上限(像素)
+	MaxMegapixels float64 // 像素总数上限(百万)
+	Format        string  // 输出格式 png / jpeg, 为空时 png 与 gif 输出 png, 其余输出 jpeg
+	Quality       int     // JPEG 初始质量, 默认 90
+	MinQuality    int     // 压缩到 MaxBytes 时 JPEG 最低质量, 默认 50
+	MaxBytes      int64   // 输出文件大小上限, 超出时逐步降低质量再缩小尺寸
+}
+
+// ImagePreprocessRes 图片预处理结果, 输出不含任何元数据
+type ImagePreprocessRes struct {
+	Data        []byte
+	Format      string // png / jpeg
+	ContentType string
+	Ext         string // .png / .jpg
+	Width       int
+	Height      int
+}
+
+// PreprocessImageFile 预处理本地图片
+func PreprocessImageFile(ctx context.Context, filePath string, opt *ImagePreprocessOption) (res *ImagePreprocessRes, err error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return PreprocessImage(ctx, file, opt)
+}
+
+// PreprocessImage 按 EXIF 自动旋转、去除元数据、限制尺寸并转为 PNG 或 JPEG, 设置 MaxBytes 时压缩到不超过该大小
+func PreprocessImage(ctx context.Context, r io.Reader, opt *ImagePreprocessOption) (res *ImagePreprocessRes, err error) {
+	if opt == nil {
+		opt = &ImagePreprocessOption{}
+	}
+	if opt.Quality <= 0 || opt.Quality > 100 {
+		opt.Quality = 90
+	}
+	if opt.MinQuality <= 0 || opt.MinQuality > opt.Quality {
+		opt.MinQuality = min(50, opt.Quality)
+	}
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	src, srcFormat, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("decode image fail: %w", err)
+	}
+	format := opt.Format
+	if format == "jpg" {
+		format = ImageFormatJPEG
+	}
+	if format == "" {
+		format = ImageFormatJPEG
+		if srcFormat == "png" || srcFormat == "gif" {
+			format = ImageFormatPNG
+		}
+	}
+	if format != ImageFormatPNG && format != ImageFormatJPEG {
+		return nil, fmt.Errorf("unsupported output format: %s", opt.Format)
+	}
+	if srcFormat == "jpeg" {
+		src = applyExifOrientation(src, jpegExifOrientation(raw))
+	}
+
+	width, height := fitImageSize(src.Bounds().Dx(), src.Bounds().Dy(), opt.MaxDimension, opt.MaxMegapixels)
+	quality := opt.Quality
+	for {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		img := resizeImage(src, width, height)
+		data, err := encodeImage(img, format, quality)
+		if err != nil {
+			return nil, err
+		}
+		if opt.MaxBytes <= 0 || int64(len(data)) <= opt.MaxBytes {
+			res = &ImagePreprocessRes{
+				Data:   data,
+				Format: format,
+				Width:  width,
+				Height: height,
+			}
+			if format == ImageFormatPNG {
+				res.ContentType, res.Ext = "image/png", ".png"
+			} else {
+				res.ContentType, res.Ext = "image/jpeg", ".jpg"
+			}
+			return res, nil
+		}
+		// 先降低 JPEG 质量, 到达下限后再缩小尺寸
+		if format == ImageFormatJPEG && quality-10 >= opt.MinQuality {
+			quality -= 10
+			continue
+		}
+		width, height = int(float64(width)*0.85), int(float64(height)*0.85)
+		if width < 16 || height < 16 {
+			return nil, fmt.Errorf("image cannot be compressed under %d bytes", opt.MaxBytes)
+		}
+	}
+}
+
+// fitImageSize 按最长边与像素总数上限等比缩放, 不放大
+func fitImageSize(width, height int, maxDimension int, maxMegapixels float64) (int, int) {
+	scale := 1.0
+	if maxDimension > 0 && max(width, height) > maxDimension {
+		scale = math.Min(scale, float64(maxDimension)/float64(max(width, height)))
+	}
+	if maxMegapixels > 0 && float64(width*height) > maxMegapixels*1e6 {
+		scale = math.Min(scale, math.Sqrt(maxMegapixels*1e6/float64(width*height)))
+	}
+	if scale >= 1 {
+		return width, height
+	}
+	return max(1, int(float64(width)*scale)), max(1, int(float64(height)*scale))
+}
+
+func resizeImage(src image.Image, width, height int) image.Image {
+	if src.Bounds().Dx() == width && src.Bounds().Dy() == height {
+		return src
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+	return dst
+}
+
+func encodeImage(img image.Image, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if format == ImageFormatPNG {
+		encoder := &png.Encoder{CompressionLevel: png.BestCompression}
+		if err := encoder.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	// JPEG 不支持透明通道, 先铺白底
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Over)
+	if err := jpeg.Encode(&buf, rgba, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// jpegExifOrientation 读取 JPEG APP1 段中的 EXIF Orientation, 读取失败时返回 1
+func jpegExifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return tiffOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifdOffset := int(order.Uint32(tiff[4:8]))
+	if ifdOffset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[ifdOffset : ifdOffset+2]))
+	for i := 0; i < count; i++ {
+		entry := ifdOffset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// applyExifOrientation 按 EXIF Orientation(1-8) 旋转或翻转图片
+func applyExifOrientation(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, src.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,133 @@
+package utility
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+var (
+	cornerTL = color.NRGBA{R: 255, A: 255}
+	cornerTR = color.NRGBA{G: 255, A: 255}
+	cornerBL = color.NRGBA{B: 255, A: 255}
+	cornerBR = color.NRGBA{R: 255, G: 255, A: 255}
+)
+
+// cornerImage 返回四角颜色不同的图片
+func cornerImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	img.Set(0, 0, cornerTL)
+	img.Set(w-1, 0, cornerTR)
+	img.Set(0, h-1, cornerBL)
+	img.Set(w-1, h-1, cornerBR)
+	return img
+}
+
+// exifSegment 构造只包含 Orientation 的 APP1 段
+func exifSegment(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// jpegWithExif 在 SOI 之后插入 EXIF 段
+func jpegWithExif(t *testing.T, img image.Image, segment []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func TestApplyExifOrientation(t *testing.T) {
+	tests := []struct {
+		orientation int
+		swap        bool // 宽高互换
+		topLeft     color.NRGBA
+		topRight    color.NRGBA
+	}{
+		{1, false, cornerTL, cornerTR},
+		{2, false, cornerTR, cornerTL}, // 水平翻转
+		{3, false, cornerBR, cornerBL}, // 旋转 180
+		{4, false, cornerBL, cornerBR}, // 垂直翻转
+		{5, true, cornerTL, cornerBL},  // 沿主对角线翻转
+		{6, true, cornerBL, cornerTL},  // 顺时针旋转 90
+		{7, true, cornerBR, cornerTR},  // 沿副对角线翻转
+		{8, true, cornerTR, cornerBR},  // 逆时针旋转 90
+	}
+	for _, tt := range tests {
+		src := cornerImage(3, 2)
+		dst := applyExifOrientation(src, tt.orientation)
+		w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+		if tt.swap && (w != 2 || h != 3) || !tt.swap && (w != 3 || h != 2) {
+			t.Errorf("orientation %d: size = %dx%d", tt.orientation, w, h)
+			continue
+		}
+		if got := color.NRGBAModel.Convert(dst.At(0, 0)); got != tt.topLeft {
+			t.Errorf("orientation %d: top left = %v, want %v", tt.orientation, got, tt.topLeft)
+		}
+		if got := color.NRGBAModel.Convert(dst.At(w-1, 0)); got != tt.topRight {
+			t.Errorf("orientation %d: top right = %v, want %v", tt.orientation, got, tt.topRight)
+		}
+	}
+}
+
+func TestJpegExifOrientation(t *testing.T) {
+	img := cornerImage(8, 8)
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{name: "little endian", data: jpegWithExif(t, img, exifSegment(binary.LittleEndian, 6)), want: 6},
+		{name: "big endian", data: jpegWithExif(t, img, exifSegment(binary.BigEndian, 8)), want: 8},
+		{name: "invalid value", data: jpegWithExif(t, img, exifSegment(binary.BigEndian, 9)), want: 1},
+		{name: "no exif", data: jpegWithExif(t, img, nil), want: 1},
+		{name: "truncated", data: jpegWithExif(t, img, exifSegment(binary.BigEndian, 6))[:20], want: 1},
+		{name: "not jpeg", data: []byte("GIF89a"), want: 1},
+	}
+	for _, tt := range tests {
+		if got := jpegExifOrientation(tt.data); got != tt.want {
+			t.Errorf("%s: jpegExifOrientation() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPreprocessImageAutoOrient(t *testing.T) {
+	data := jpegWithExif(t, image.NewNRGBA(image.Rect(0, 0, 40, 20)), exifSegment(binary.BigEndian, 6))
+	res, err := PreprocessImage(context.Background(), bytes.NewReader(data), &ImagePreprocessOption{MaxDimension: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Width != 10 || res.Height != 20 || res.Format != ImageFormatJPEG {
+		t.Fatalf("result = %dx%d %s, want 10x20 jpeg", res.Width, res.Height, res.Format)
+	}
+	// 输出不再携带 EXIF, 避免被再次旋转
+	if jpegExifOrientation(res.Data) != 1 || bytes.Contains(res.Data, []byte("Exif\x00\x00")) {
+		t.Fatal("output keeps exif metadata")
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(res.Data))
+	if err != nil || cfg.Width != 10 || cfg.Height != 20 {
+		t.Fatalf("decoded size = %dx%d, %v", cfg.Width, cfg.Height, err)
+	}
+}
@@ -0,0 +1,131 @@
+package utility
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// urlIdleTimeout 读取响应体时两次读到数据之间的最长间隔, 响应体以流的方式读取, 不限制总时长
+	urlIdleTimeout = 60 * time.Second
+	// urlClient OpenURL 使用的客户端, 限制连接、TLS 握手与响应头的等待时间
+	urlClient = &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 30 * time.Second,
+			IdleConnTimeout:       90 * time.Second,
+			MaxIdleConns:          100,
+			ForceAttemptHTTP2:     true,
+		},
+	}
+)
+
+var errUrlIdleTimeout = errors.New("read response body timeout")
+
+// UrlMeta 远程文件信息
+type UrlMeta struct {
+	FileName      string // 优先取 Content-Disposition, 其次取 URL 路径最后一截
+	ContentType   string
+	ContentLength int64 // 未知时为 -1
+}
+
+// OpenURL 打开远程文件, 调用方负责关闭返回的 io.ReadCloser;
+// 响应头超过 30s 未返回或响应体超过 60s 没有新数据时请求失败, 总时长由 ctx 控制
+func OpenURL(ctx context.Context, rawURL string) (body io.ReadCloser, meta *UrlMeta, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid url: %w", err)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	resp, err := urlClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return nil, nil, fmt.Errorf("bad status: %s", resp.Status)
+	}
+	meta = &UrlMeta{
+		FileName:      path.Base(u.Path),
+		ContentType:   resp.Header.Get("Content-Type"),
+		ContentLength: resp.ContentLength,
+	}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		meta.FileName = path.Base(params["filename"])
+	}
+	if meta.FileName == "" || meta.FileName == "/" || meta.FileName == "." {
+		meta.FileName = "file"
+	}
+	return newIdleTimeoutBody(resp.Body, cancel, urlIdleTimeout), meta, nil
+}
+
+// idleTimeoutBody 读取间隔超过 timeout 时取消请求, 使阻塞的 Read 返回
+type idleTimeoutBody struct {
+	body     io.ReadCloser
+	cancel   context.CancelFunc
+	timeout  time.Duration
+	timer    *time.Timer
+	timedOut atomic.Bool
+}
+
+func newIdleTimeoutBody(body io.ReadCloser, cancel context.CancelFunc, timeout time.Duration) *idleTimeoutBody {
+	b := &idleTimeoutBody{body: body, cancel: cancel, timeout: timeout}
+	b.timer = time.AfterFunc(timeout, func() {
+		b.timedOut.Store(true)
+		cancel()
+	})
+	return b
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (n int, err error) {
+	n, err = b.body.Read(p)
+	if err != nil && err != io.EOF && b.timedOut.Load() {
+		return n, errUrlIdleTimeout
+	}
+	if n > 0 {
+		b.timer.Reset(b.timeout)
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	err := b.body.Close()
+	b.cancel()
+	return err
+}
+
+// DownloadTo 将远程文件写入 w, 不落盘, 返回写入的字节数
+func DownloadTo(ctx context.Context, rawURL string, w io.Writer) (n int64, err error) {
+	body, meta, err := OpenURL(ctx, rawURL)
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	n, err = io.Copy(w, body)
+	if err != nil {
+		return n, fmt.Errorf("download failed: %w", err)
+	}
+	if meta.ContentLength >= 0 && n != meta.ContentLength {
+		return n, fmt.Errorf("size mismatch, expect: %d, got: %d", meta.ContentLength, n)
+	}
+	return n, nil
+}
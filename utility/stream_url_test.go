@@ -0,0 +1,146 @@
+package utility
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing.png":
+			http.NotFound(w, r)
+			return
+		case "/download":
+			w.Header().Set("Content-Disposition", `attachment; filename="dir/名字.png"`)
+		case "/bad-disposition.png":
+			w.Header().Set("Content-Disposition", `attachment; filename=`)
+		}
+		w.Header().Set("Content-Type", "image/png")
+		io.WriteString(w, "content of "+r.URL.Path)
+	}))
+	defer server.Close()
+	tests := []struct {
+		name     string
+		path     string
+		wantName string
+		wantErr  string
+	}{
+		{name: "path name", path: "/a/b.png", wantName: "b.png"},
+		{name: "content disposition", path: "/download", wantName: "名字.png"},
+		{name: "invalid content disposition", path: "/bad-disposition.png", wantName: "bad-disposition.png"},
+		{name: "empty path", path: "/", wantName: "file"},
+		{name: "status error", path: "/missing.png", wantErr: "bad status: 404 Not Found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, meta, err := OpenURL(context.Background(), server.URL+tt.path)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("OpenURL() err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer body.Close()
+			content, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "content of " + tt.path
+			if string(content) != want || meta.FileName != tt.wantName || meta.ContentType != "image/png" || meta.ContentLength != int64(len(want)) {
+				t.Fatalf("OpenURL() = %q, %+v", content, meta)
+			}
+		})
+	}
+	if _, _, err := OpenURL(context.Background(), "://bad"); err == nil {
+		t.Fatal("OpenURL() of invalid url should fail")
+	}
+}
+
+// stallServer 写出部分响应体后阻塞, 直到测试结束
+func stallServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		io.WriteString(w, "partial")
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(func() {
+		close(release)
+		server.Close()
+	})
+	return server
+}
+
+func TestOpenURLCancel(t *testing.T) {
+	server := stallServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	body, _, err := OpenURL(ctx, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err = io.ReadAll(body); !errors.Is(err, context.Canceled) {
+		t.Fatalf("read err = %v, want context.Canceled", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("read returned after %s", d)
+	}
+
+	// 连接前已取消
+	if _, _, err = OpenURL(ctx, server.URL); !errors.Is(err, context.Canceled) {
+		t.Fatalf("OpenURL() err = %v, want context.Canceled", err)
+	}
+}
+
+func TestOpenURLIdleTimeout(t *testing.T) {
+	old := urlIdleTimeout
+	urlIdleTimeout = 50 * time.Millisecond
+	defer func() { urlIdleTimeout = old }()
+	server := stallServer(t)
+	body, _, err := OpenURL(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if _, err = io.ReadAll(body); !errors.Is(err, errUrlIdleTimeout) {
+		t.Fatalf("read err = %v, want idle timeout", err)
+	}
+}
+
+func TestDownloadTo(t *testing.T) {
+	content := strings.Repeat("data", 10000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		io.WriteString(w, content)
+	}))
+	defer server.Close()
+	var buf bytes.Buffer
+	n, err := DownloadTo(context.Background(), server.URL+"/ok", &buf)
+	if err != nil || n != int64(len(content)) || buf.String() != content {
+		t.Fatalf("DownloadTo() = %d, %v", n, err)
+	}
+	if _, err = DownloadTo(context.Background(), server.URL+"/fail", io.Discard); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Fatalf("DownloadTo() err = %v, want bad status", err)
+	}
+}